@@ -0,0 +1,220 @@
+package exitplan
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CallbackOption changes how a callback registered with AddWithOptions is executed.
+type CallbackOption func(c *callback)
+
+// callback is a registered ExitOperation along with its options.
+type callback struct {
+	name       string
+	op         ExitOperation
+	index      int
+	quiet      bool
+	sequential bool
+	preStop    bool
+	lease      bool
+	mustRun    bool
+	source     triggerSource
+	timeout    time.Duration
+	retries    int
+	backoff    time.Duration
+	meta       map[string]string
+	idempotent bool
+	cond       func() bool
+}
+
+// triggerSource restricts a callback to the shutdowns triggered by a signal, or not.
+type triggerSource int
+
+const (
+	anySource triggerSource = iota
+	signalSource
+	programmaticSource
+)
+
+// runsFor reports if c runs for a shutdown triggered by sig, nil when it wasn't by a signal.
+func (c *callback) runsFor(sig os.Signal) bool {
+	switch c.source {
+	case signalSource:
+		return sig != nil
+	case programmaticSource:
+		return sig == nil
+	}
+	return true
+}
+
+// runningFor returns the callbacks that run for a shutdown triggered by sig.
+func runningFor(callbacks []*callback, sig os.Signal) []*callback {
+	running := make([]*callback, 0, len(callbacks))
+	for _, c := range callbacks {
+		if c.runsFor(sig) {
+			running = append(running, c)
+		}
+	}
+	return running
+}
+
+// WithQuietSuccess will suppress the "disposing" and "disposed gracefully" log lines
+//  for the callback. Failures are always logged.
+func WithQuietSuccess() CallbackOption {
+	return func(c *callback) {
+		c.quiet = true
+	}
+}
+
+// WithTimeout will give up waiting on the callback once d has elapsed, letting the
+//  shutdown proceed while it's abandoned. By default it's bound by the plan Timeout.
+func WithTimeout(d time.Duration) CallbackOption {
+	return func(c *callback) {
+		c.timeout = d
+	}
+}
+
+// WithRetry will call the callback again up to retries times when it fails, waiting
+//  backoff before the first retry and doubling it for each of the next ones.
+// The retries are bound by the deadline of the callbacks, a backoff that would
+//  exceed it gives up with the last error.
+func WithRetry(retries int, backoff time.Duration) CallbackOption {
+	return func(c *callback) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithIdempotent will mark the callback as safe to run more than once, e.g. by both
+//  the normal path and the forced exit of AddMustRun. Otherwise it runs at most once.
+func WithIdempotent() CallbackOption {
+	return func(c *callback) {
+		c.idempotent = true
+	}
+}
+
+// ranGuard keeps the names of the callbacks that have been started, so the ones
+//  that aren't idempotent run at most once.
+type ranGuard struct {
+	mu    sync.Mutex
+	names map[string]bool
+}
+
+// claim reports if c may run, marking it as started.
+func (g *ranGuard) claim(c *callback) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.names == nil {
+		g.names = make(map[string]bool)
+	}
+	if g.names[c.name] && !c.idempotent {
+		return false
+	}
+	g.names[c.name] = true
+	return true
+}
+
+// started reports if c has been claimed already and may not run again.
+func (g *ranGuard) started(c *callback) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.names[c.name] && !c.idempotent
+}
+
+// WithMeta will tag the callback with meta, like its owner or a docs link, included
+//  in its log lines and in Describe. It's copied, changing meta later has no effect.
+func WithMeta(meta map[string]string) CallbackOption {
+	return func(c *callback) {
+		c.meta = make(map[string]string, len(meta))
+		for k, v := range meta {
+			c.meta[k] = v
+		}
+	}
+}
+
+// label returns the name of c along with its metadata, sorted by key.
+func (c *callback) label() string {
+	if len(c.meta) == 0 {
+		return c.name
+	}
+
+	keys := make([]string, 0, len(c.meta))
+	for k := range c.meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + c.meta[k]
+	}
+	return c.name + " [" + strings.Join(pairs, " ") + "]"
+}
+
+// byIndex sorts callbacks in the order they were registered.
+type byIndex []*callback
+
+func (b byIndex) Len() int           { return len(b) }
+func (b byIndex) Less(i, j int) bool { return b[i].index < b[j].index }
+func (b byIndex) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+// splitCallbacks returns the registered callbacks split into the ones to run
+//  concurrently and the ones to run sequentially, in registration order.
+// The sequential ones are in reverse when ReverseOrder is set.
+func (p *ExecutionPlan) splitCallbacks() (concurrent, sequential []*callback) {
+	p.callbacksMutex.RLock()
+	defer p.callbacksMutex.RUnlock()
+
+	for _, c := range p.callbacks {
+		if c.preStop || c.lease {
+			continue
+		}
+		if c.sequential {
+			sequential = append(sequential, c)
+		} else {
+			concurrent = append(concurrent, c)
+		}
+	}
+	sort.Sort(byIndex(concurrent))
+	if p.ReverseOrder {
+		sort.Sort(sort.Reverse(byIndex(sequential)))
+	} else {
+		sort.Sort(byIndex(sequential))
+	}
+
+	return concurrent, sequential
+}
+
+// mustRunCallbacks returns the callbacks registered with AddMustRun, in registration order.
+func (p *ExecutionPlan) mustRunCallbacks() []*callback {
+	return p.callbacksWhere(func(c *callback) bool { return c.mustRun })
+}
+
+// preStopCallbacks returns the callbacks registered with AddPreStop, in registration order.
+func (p *ExecutionPlan) preStopCallbacks() []*callback {
+	return p.callbacksWhere(func(c *callback) bool { return c.preStop })
+}
+
+// leaseCallbacks returns the callbacks registered with AddLeaseRelease, in registration order.
+func (p *ExecutionPlan) leaseCallbacks() []*callback {
+	return p.callbacksWhere(func(c *callback) bool { return c.lease })
+}
+
+// callbacksWhere returns the callbacks matching keep, in registration order.
+func (p *ExecutionPlan) callbacksWhere(keep func(c *callback) bool) (callbacks []*callback) {
+	p.callbacksMutex.RLock()
+	defer p.callbacksMutex.RUnlock()
+
+	for _, c := range p.callbacks {
+		if keep(c) {
+			callbacks = append(callbacks, c)
+		}
+	}
+	sort.Sort(byIndex(callbacks))
+
+	return callbacks
+}
@@ -0,0 +1,125 @@
+package exitplan
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nhalstead/exitplan/pool"
+)
+
+func TestRanGuardClaimsOnce(t *testing.T) {
+	for _, idempotent := range []bool{false, true} {
+		var g ranGuard
+		c := &callback{name: "db", idempotent: idempotent}
+
+		var claimed int32
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if g.claim(c) {
+					atomic.AddInt32(&claimed, 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		want := int32(1)
+		if idempotent {
+			want = 50
+		}
+		if claimed != want {
+			t.Errorf("idempotent=%v: claimed %d times, want %d", idempotent, claimed, want)
+		}
+	}
+}
+
+func TestMustRunRunsOnceWhenRacingForceExit(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		p, _ := newTestPlan(0, time.Second)
+
+		var calls int32
+		_ = p.AddMustRun("flush", func(ctx context.Context) error {
+			atomic.AddInt32(&calls, 1)
+			return nil
+		})
+
+		// The normal completion and the force exit path, which runs the must run
+		//  callbacks again, both reach the callback.
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = p.Shutdown(context.Background())
+		}()
+		go func() {
+			defer wg.Done()
+			p.runMustRun()
+		}()
+		wg.Wait()
+
+		if calls != 1 {
+			t.Fatalf("flush was called %d times, want 1", calls)
+		}
+	}
+}
+
+func TestMustRunRunsWithSaturatedExecutor(t *testing.T) {
+	p, clock := newTestPlan(0, time.Second)
+	p.Executor = pool.New(1)
+
+	hung := make(chan struct{})
+	defer close(hung)
+	_ = p.Add("hung", func(ctx context.Context) error {
+		<-hung
+		return nil
+	})
+	var calls int32
+	_ = p.AddMustRun("marker", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p.Shutdown(context.Background())
+	}()
+
+	// hung holds the only worker of the pool, as it would at a forced exit.
+	awaitTimers(t, clock, 2)
+	ran := make(chan struct{})
+	go func() {
+		defer close(ran)
+		p.runMustRun()
+	}()
+	select {
+	case <-ran:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the must run callbacks are blocked on the Executor")
+	}
+	if calls != 1 {
+		t.Fatalf("marker was called %d times by the forced exit, want 1", calls)
+	}
+
+	for start := time.Now(); ; clock.Advance(50 * time.Millisecond) {
+		select {
+		case <-errc:
+		case <-time.After(time.Millisecond):
+			if time.Since(start) > 2*time.Second {
+				t.Fatal("shutdown has not completed")
+			}
+			continue
+		}
+		break
+	}
+	if calls != 1 {
+		t.Errorf("marker was called %d times, want 1", calls)
+	}
+	if err := p.Result().Errors["marker"]; err != nil {
+		t.Errorf("error of marker = %v, want nil", err)
+	}
+}
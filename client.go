@@ -0,0 +1,81 @@
+package exitplan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrTerminating is returned by PollReady when the endpoint reports it's terminating.
+var ErrTerminating = errors.New("exitplan: endpoint is terminating")
+
+// PollInterval is the delay between requests made by PollReady.
+var PollInterval = 250 * time.Millisecond
+
+// PollReady is the client side counterpart of HandlerFunc.
+// It will request url until the endpoint reports it's ready (or degraded), returning nil,
+//  or terminating, returning ErrTerminating. Any other response (or a failed request)
+//  is retried until timeout has elapsed.
+func PollReady(url string, timeout time.Duration) error {
+	client := &http.Client{Timeout: PollInterval * 4}
+	deadline := time.Now().Add(timeout)
+
+	var lastErr error
+	for {
+		ready, err := pollOnce(client, url)
+		if err == nil {
+			if ready {
+				return nil
+			}
+			return ErrTerminating
+		}
+		lastErr = err
+
+		if time.Now().Add(PollInterval).After(deadline) {
+			return fmt.Errorf("exitplan: %s not ready after %s: %w", url, timeout, lastErr)
+		}
+		time.Sleep(PollInterval)
+	}
+}
+
+// pollOnce makes a single request to url and reports if it's ready.
+// An error is returned if the state could not be determined.
+func pollOnce(client *http.Client, url string) (bool, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+
+	status := strings.TrimSpace(string(body))
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "application/json" {
+		var payload struct {
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return false, err
+		}
+		status = payload.Status
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK && status == "ok":
+		return true, nil
+	case resp.Header.Get("X-Health") == "degraded" && resp.StatusCode != http.StatusServiceUnavailable:
+		// Degraded is still serving.
+		return true, nil
+	case resp.StatusCode == http.StatusServiceUnavailable && status == "terminating":
+		return false, nil
+	}
+	return false, fmt.Errorf("unexpected response %d %q", resp.StatusCode, status)
+}
@@ -0,0 +1,73 @@
+package exitplan
+
+import (
+	"os"
+)
+
+// Clone returns a new ExecutionPlan with a copy of the settings, signals, health checks
+//  and registered callbacks of p, to be used as a template for similar plans.
+// The runtime state (terminating, the State, exit chans and listeners) is not copied.
+// Cloning a plan that's already started is unsupported.
+func (p *ExecutionPlan) Clone() *ExecutionPlan {
+	c := NewPlanWithTimer(p.timeouts())
+
+	c.Signals = append([]os.Signal(nil), p.signals()...)
+	c.SkipDrainSignals = append([]os.Signal(nil), p.SkipDrainSignals...)
+	if p.SignalMode != nil {
+		c.SignalMode = make(map[os.Signal]ShutdownMode, len(p.SignalMode))
+		for sig, mode := range p.SignalMode {
+			c.SignalMode[sig] = mode
+		}
+	}
+	c.SequentialFirst = p.SequentialFirst
+	c.GradePeriodJitter = p.GradePeriodJitter
+	c.ReverseOrder = p.ReverseOrder
+	c.KillOnTimeout = p.KillOnTimeout
+	c.DisableForceExit = p.DisableForceExit
+	c.RetryNotReadyDelay = p.RetryNotReadyDelay
+	c.ReloadSignals = append([]os.Signal(nil), p.ReloadSignals...)
+	c.ReloadHandler = p.ReloadHandler
+	c.OnNonTerminatingSignal = p.OnNonTerminatingSignal
+	c.FirstSignalAction = p.FirstSignalAction
+	c.SecondSignalAction = p.SecondSignalAction
+	c.ConfirmOnSignal = p.ConfirmOnSignal
+	c.Executor = p.Executor
+	c.Clock = p.Clock
+	c.CorrelationID = p.CorrelationID
+	c.ReadinessFailDelay = p.ReadinessFailDelay
+	c.ReadinessDrainedProbes = p.ReadinessDrainedProbes
+	c.AwaitProbe = p.AwaitProbe
+	c.RecordOutcome = p.RecordOutcome
+	c.GoroutineThreshold = p.GoroutineThreshold
+	c.Logger = p.Logger
+	c.LogLevel = p.LogLevel
+	c.GroupLogs = p.GroupLogs
+	c.DegradedStatusCode = p.DegradedStatusCode
+	c.RequireStarted = p.RequireStarted
+	c.MaxCallbacks = p.MaxCallbacks
+	c.MaxConcurrency = p.MaxConcurrency
+	c.SuccessThreshold = p.SuccessThreshold
+	c.finalCallback = p.finalCallback
+	c.finalRetries, c.finalBackoff = p.finalRetries, p.finalBackoff
+	c.drainGauges = append([]func() int(nil), p.drainGauges...)
+	if p.tracer != nil {
+		c.EnableTrace()
+	}
+
+	p.healthMutex.RLock()
+	for name, check := range p.healthChecks {
+		c.AddHealthCheck(name, check)
+	}
+	p.healthMutex.RUnlock()
+
+	p.callbacksMutex.RLock()
+	defer p.callbacksMutex.RUnlock()
+
+	for name, cb := range p.callbacks {
+		cp := *cb
+		c.callbacks[name] = &cp
+	}
+	c.callbackIndex = p.callbackIndex
+
+	return c
+}
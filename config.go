@@ -0,0 +1,115 @@
+package exitplan
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// PlanConfig is the declarative definition of a plan, see LoadPlanConfig.
+type PlanConfig struct {
+	GradePeriod     *time.Duration   `yaml:"gradePeriod"`
+	Timeout         *time.Duration   `yaml:"timeout"`
+	Signals         []string         `yaml:"signals"`
+	SequentialFirst bool             `yaml:"sequentialFirst"`
+	Callbacks       []CallbackConfig `yaml:"callbacks"`
+}
+
+// CallbackConfig is the metadata of a callback in a PlanConfig.
+// The callbacks are registered in the order they are listed.
+type CallbackConfig struct {
+	Name       string        `yaml:"name"`
+	Timeout    time.Duration `yaml:"timeout"`
+	Sequential bool          `yaml:"sequential"`
+	Quiet      bool          `yaml:"quiet"`
+}
+
+// LoadPlanConfig will read a PlanConfig in YAML (or JSON) from r, e.g.
+//
+//   gradePeriod: 5s
+//   timeout: 20s
+//   signals: [SIGINT, SIGTERM]
+//   callbacks:
+//     - name: http
+//       timeout: 10s
+//     - name: db
+//       sequential: true
+//
+// The signal names are the ones recognized by NewPlanFromEnv.
+func LoadPlanConfig(r io.Reader) (*PlanConfig, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg PlanConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("exitplan: invalid plan config: %w", err)
+	}
+
+	for _, name := range cfg.Signals {
+		if _, ok := parseSignal(name); !ok {
+			return nil, fmt.Errorf("exitplan: invalid plan config: unknown signal %q", name)
+		}
+	}
+	for i, c := range cfg.Callbacks {
+		if c.Name == "" {
+			return nil, fmt.Errorf("exitplan: invalid plan config: callback %d has no name", i)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// ApplyConfig will apply the settings of cfg to the plan and register the callbacks
+//  it declares, binding each one by name to its ExitOperation in ops.
+// An error is returned, with nothing applied, if a callback has no ExitOperation in ops.
+// Registering the callbacks stops at the first error, e.g. when MaxCallbacks is exceeded.
+// The GradePeriod and Timeout are set like SetGradePeriod and SetTimeout, returning
+//  ErrShutdownStarted once the shutdown has begun.
+func (p *ExecutionPlan) ApplyConfig(cfg *PlanConfig, ops map[string]ExitOperation) error {
+	for _, c := range cfg.Callbacks {
+		if _, ok := ops[c.Name]; !ok {
+			return fmt.Errorf("exitplan: unknown callback %q in plan config", c.Name)
+		}
+	}
+
+	if cfg.GradePeriod != nil {
+		if err := p.SetGradePeriod(*cfg.GradePeriod); err != nil {
+			return err
+		}
+	}
+	if cfg.Timeout != nil {
+		if err := p.SetTimeout(*cfg.Timeout); err != nil {
+			return err
+		}
+	}
+	if len(cfg.Signals) > 0 {
+		sigs := make([]os.Signal, 0, len(cfg.Signals))
+		for _, name := range cfg.Signals {
+			sig, _ := parseSignal(name)
+			sigs = append(sigs, sig)
+		}
+		p.SetSignals(sigs...)
+	}
+	p.SequentialFirst = cfg.SequentialFirst
+
+	for _, c := range cfg.Callbacks {
+		err := p.register(&callback{
+			name:       c.Name,
+			op:         ops[c.Name],
+			quiet:      c.Quiet,
+			sequential: c.Sequential,
+			timeout:    c.Timeout,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
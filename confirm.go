@@ -0,0 +1,54 @@
+package exitplan
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PromptConfirm can be used as the ConfirmOnSignal hook of a CLI tool.
+// It will ask "Shut down? [y/N]" on stderr and read the answer from stdin.
+func PromptConfirm(sig os.Signal) bool {
+	fmt.Fprintf(os.Stderr, "\nreceived %s, shut down? [y/N] ", sig)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// isTerminal reports if stdin is attached to a terminal.
+func isTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm will ask ConfirmOnSignal if sig should shut down the plan, it's always
+//  confirmed when stdin isn't a terminal. Another signal arriving on s while waiting
+//  for the answer confirms it.
+func (p *ExecutionPlan) confirm(sig os.Signal, s <-chan os.Signal) bool {
+	if p.ConfirmOnSignal == nil || !isTerminal() {
+		return true
+	}
+
+	answer := make(chan bool, 1)
+	go func() {
+		answer <- p.ConfirmOnSignal(sig)
+	}()
+
+	select {
+	case ok := <-answer:
+		if !ok {
+			fmt.Fprintf(os.Stderr, "ignoring %s\n", sig)
+		}
+		return ok
+	case <-s:
+		return true
+	}
+}
@@ -0,0 +1,87 @@
+package exitplan
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+type signalKey struct{}
+
+// SignalFromContext returns the signal that has triggered the shutdown, carried by
+//  the ctx given to the callbacks. It's nil and false for a shutdown that wasn't
+//  triggered by a signal, like a call to Shutdown.
+func SignalFromContext(ctx context.Context) (os.Signal, bool) {
+	sig, ok := ctx.Value(signalKey{}).(os.Signal)
+	return sig, ok
+}
+
+// TriggeringSignal returns the signal that has triggered the shutdown, nil until it
+//  has begun or when it wasn't triggered by a signal.
+func (p *ExecutionPlan) TriggeringSignal() os.Signal {
+	p.resultMutex.RLock()
+	defer p.resultMutex.RUnlock()
+	return p.signal
+}
+
+// withSignal will keep sig as the TriggeringSignal, returning ctx carrying it.
+func (p *ExecutionPlan) withSignal(ctx context.Context, sig os.Signal) context.Context {
+	if sig == nil {
+		return ctx
+	}
+
+	p.resultMutex.Lock()
+	p.signal = sig
+	p.resultMutex.Unlock()
+
+	return context.WithValue(ctx, signalKey{}, sig)
+}
+
+// ShutdownContext returns a context derived from parent that's canceled the moment the
+//  plan is terminating. From then on its Deadline is the end of the shutdown budget
+//  (the GradePeriod and Timeout from when terminating began, or the deadline of the
+//  callbacks once they run), so a worker can stop promptly and know how long it has to
+//  wrap up. Before that it's the deadline of parent.
+func (p *ExecutionPlan) ShutdownContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	stop := p.AfterTerminating(cancel)
+
+	return &shutdownContext{Context: ctx, plan: p}, func() {
+		stop()
+		cancel()
+	}
+}
+
+// shutdownContext reports the deadline of the shutdown once the plan is terminating.
+type shutdownContext struct {
+	context.Context
+	plan *ExecutionPlan
+}
+
+func (c *shutdownContext) Deadline() (time.Time, bool) {
+	deadline, ok := c.plan.shutdownDeadline()
+	if !ok {
+		return c.Context.Deadline()
+	}
+	if d, ok := c.Context.Deadline(); ok && d.Before(deadline) {
+		return d, true
+	}
+	return deadline, true
+}
+
+// shutdownDeadline returns when the shutdown is expected to be done, false when
+//  the plan is not terminating yet.
+func (p *ExecutionPlan) shutdownDeadline() (time.Time, bool) {
+	if deadline := p.callbacksDeadline(); !deadline.IsZero() {
+		return deadline, true
+	}
+
+	p.isTerminatingMutex.RLock()
+	defer p.isTerminatingMutex.RUnlock()
+
+	if !p.isTerminating {
+		return time.Time{}, false
+	}
+	gradePeriod, timeout := p.timeouts()
+	return p.terminatingSince.Add(gradePeriod + timeout), true
+}
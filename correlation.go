@@ -0,0 +1,48 @@
+package exitplan
+
+import (
+	"context"
+)
+
+type correlationKey struct{}
+
+// WithCorrelationID returns a copy of ctx carrying id, to tie the logs of a shutdown
+//  spanning many services together. When the ctx given to Start or Shutdown carries one
+//  it's used over the CorrelationID of the plan.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationIDFromContext returns the correlation ID carried by ctx, the ctx given to
+//  the callbacks carries the one of the shutdown.
+func CorrelationIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(correlationKey{}).(string)
+	return id, ok
+}
+
+// correlate will resolve the correlation ID of the shutdown from ctx or the plan,
+//  returning ctx carrying it for the callbacks.
+func (p *ExecutionPlan) correlate(ctx context.Context) context.Context {
+	id, ok := CorrelationIDFromContext(ctx)
+	if !ok {
+		id = p.CorrelationID
+	}
+	if id == "" {
+		return ctx
+	}
+
+	p.correlation.Store(id)
+	if !ok {
+		ctx = WithCorrelationID(ctx, id)
+	}
+	return ctx
+}
+
+// correlationID returns the correlation ID of the shutdown, the CorrelationID of the
+//  plan until it has begun.
+func (p *ExecutionPlan) correlationID() string {
+	if id, ok := p.correlation.Load().(string); ok {
+		return id
+	}
+	return p.CorrelationID
+}
@@ -0,0 +1,47 @@
+package exitplan
+
+import (
+	"context"
+	"sync"
+)
+
+var (
+	defaultPlan  *ExecutionPlan
+	defaultMutex sync.Mutex
+)
+
+// Default returns the plan used by the package-level Add, Finally and Wait, like
+//  http.DefaultServeMux. It's created with NewPlan on first use unless SetDefault
+//  was called, the lazy init is safe to race from several goroutines.
+func Default() *ExecutionPlan {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+
+	if defaultPlan == nil {
+		defaultPlan = NewPlan()
+	}
+	return defaultPlan
+}
+
+// SetDefault will replace the plan returned by Default, to configure it.
+// Callbacks already registered on the previous one are not carried over.
+func SetDefault(p *ExecutionPlan) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	defaultPlan = p
+}
+
+// Add will register the handler under name on the Default plan.
+func Add(name string, handler ExitOperation) error {
+	return Default().Add(name, handler)
+}
+
+// Finally will set the final callback of the Default plan.
+func Finally(handler ExitOperation) {
+	Default().Finally(handler)
+}
+
+// Wait will start the Default plan and wait for its shutdown to complete.
+func Wait(ctx context.Context) error {
+	return Default().Wait(ctx)
+}
@@ -0,0 +1,75 @@
+package exitplan
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// WatchedSignals returns the signals the plan is listening on, the Signals (as last
+//  set with SetSignals) along with the ReloadSignals.
+func (p *ExecutionPlan) WatchedSignals() []os.Signal {
+	return p.watchedSignals()
+}
+
+// Count returns the number of registered callbacks.
+func (p *ExecutionPlan) Count() int {
+	p.callbacksMutex.RLock()
+	defer p.callbacksMutex.RUnlock()
+	return len(p.callbacks)
+}
+
+// Names returns the names of the registered callbacks, sorted.
+func (p *ExecutionPlan) Names() []string {
+	p.callbacksMutex.RLock()
+	names := make([]string, 0, len(p.callbacks))
+	for name := range p.callbacks {
+		names = append(names, name)
+	}
+	p.callbacksMutex.RUnlock()
+
+	sort.Strings(names)
+	return names
+}
+
+// Describe returns a human readable summary of the plan, its watched signals, timers
+//  and callbacks in the order they run, for diagnostics.
+func (p *ExecutionPlan) Describe() string {
+	var b strings.Builder
+
+	gradePeriod, timeout := p.timeouts()
+	fmt.Fprintf(&b, "signals: %s\n", joinSignals(p.WatchedSignals()))
+	fmt.Fprintf(&b, "grade period: %s\n", gradePeriod)
+	fmt.Fprintf(&b, "timeout: %s\n", timeout)
+
+	concurrent, sequential := p.splitCallbacks()
+	fmt.Fprintf(&b, "leases: %s\n", joinCallbacks(p.leaseCallbacks()))
+	fmt.Fprintf(&b, "prestop: %s\n", joinCallbacks(p.preStopCallbacks()))
+	if p.SequentialFirst {
+		fmt.Fprintf(&b, "sequential: %s\n", joinCallbacks(sequential))
+		fmt.Fprintf(&b, "concurrent: %s\n", joinCallbacks(concurrent))
+	} else {
+		fmt.Fprintf(&b, "concurrent: %s\n", joinCallbacks(concurrent))
+		fmt.Fprintf(&b, "sequential: %s\n", joinCallbacks(sequential))
+	}
+	fmt.Fprintf(&b, "final: %t\n", p.finalCallback != nil)
+
+	return b.String()
+}
+
+func joinSignals(sigs []os.Signal) string {
+	names := make([]string, len(sigs))
+	for i, sig := range sigs {
+		names[i] = sig.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+func joinCallbacks(callbacks []*callback) string {
+	names := make([]string, len(callbacks))
+	for i, c := range callbacks {
+		names[i] = c.label()
+	}
+	return strings.Join(names, ", ")
+}
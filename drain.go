@@ -0,0 +1,131 @@
+package exitplan
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// drainFlushInterval is the delay between the flushes of DrainHandler while waiting.
+const drainFlushInterval = time.Second
+
+// drainSummary is the JSON response of DrainHandler.
+type drainSummary struct {
+	Status    string            `json:"status"`
+	Clean     bool              `json:"clean"`
+	Errors    map[string]string `json:"errors,omitempty"`
+	Durations map[string]int64  `json:"durations_ms,omitempty"`
+}
+
+// DrainHandler is used by a controller to drain the instance over HTTP, e.g. as
+//  "POST /drain". It runs the shutdown sequence like Shutdown and holds the request
+//  open until it has completed, flushing whitespace periodically so proxies don't
+//  time it out, then responds with a JSON summary of the Result:
+//
+//   {"status":"done","clean":false,"errors":{"db":"..."},"durations_ms":{"db":12}}
+//
+// The wait is bound by the GradePeriod and Timeout (plus a second), the status
+//  being "timeout" when it's exceeded. Serve it from a server that isn't managed
+//  by the plan (see ManageServer), as shutting that one down waits for the request.
+func (p *ExecutionPlan) DrainHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	go p.fire(context.Background(), nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	ticker := time.NewTicker(drainFlushInterval)
+	defer ticker.Stop()
+	gradePeriod, timeout := p.timeouts()
+	bound := time.NewTimer(gradePeriod + timeout + time.Second)
+	defer bound.Stop()
+
+	summary := drainSummary{Status: "timeout"}
+wait:
+	for {
+		select {
+		case <-p.done:
+			summary = newDrainSummary(p.Result())
+			break wait
+		case <-ticker.C:
+			if flusher != nil {
+				_, _ = w.Write([]byte(" "))
+				flusher.Flush()
+			}
+		case <-bound.C:
+			break wait
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	_ = json.NewEncoder(w).Encode(summary)
+}
+
+func newDrainSummary(r Result) drainSummary {
+	s := drainSummary{
+		Status:    "done",
+		Clean:     r.Clean,
+		Durations: make(map[string]int64, len(r.Durations)),
+	}
+	for name, d := range r.Durations {
+		s.Durations[name] = d.Milliseconds()
+	}
+	if len(r.Errors) > 0 {
+		s.Errors = make(map[string]string, len(r.Errors))
+		for name, err := range r.Errors {
+			s.Errors[name] = err.Error()
+		}
+	}
+	return s
+}
+
+// drainStatus is the JSON response of DrainStatusHandler.
+type drainStatus struct {
+	Terminating      bool     `json:"terminating"`
+	InFlight         int64    `json:"inFlight"`
+	CallbacksPending []string `json:"callbacksPending"`
+	Phase            string   `json:"phase"`
+}
+
+// DrainStatusHandler reports the progress of the shutdown as JSON for a controller
+//  deciding when it's safe to kill the instance early, e.g. as "GET /drain/status":
+//
+//   {"terminating":true,"inFlight":2,"callbacksPending":["db"],"phase":"disposing"}
+//
+// The pending callbacks are the registered ones that have not completed, or been skipped, yet.
+// Once the shutdown has begun only the callbacks running for its trigger are pending
+//  (see AddForSignalOnly), none once it's done.
+// It's meant for automation, HandlerFunc is the one for load balancer health checks.
+func (p *ExecutionPlan) DrainStatusHandler(w http.ResponseWriter, r *http.Request) {
+	result := p.outcome.result(nil)
+	status := drainStatus{
+		Terminating:      p.IsTerminating(),
+		InFlight:         p.InFlight(),
+		CallbacksPending: make([]string, 0),
+		Phase:            p.State().String(),
+	}
+	if p.State() != Done {
+		concurrent, sequential := p.splitCallbacks()
+		callbacks := append(concurrent, sequential...)
+		if p.shutdownStarted() {
+			callbacks = runningFor(callbacks, p.TriggeringSignal())
+		}
+		for _, c := range callbacks {
+			if _, ok := result.Durations[c.name]; !ok && !containsString(result.Skipped, c.name) {
+				status.CallbacksPending = append(status.CallbacksPending, c.name)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,38 @@
+package exitplan
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDrainStatusPendingCallbacks(t *testing.T) {
+	p, _ := newTestPlan(0, time.Second)
+	_ = p.Add("db", func(ctx context.Context) error { return nil })
+	_ = p.AddForSignalOnly("notify", func(ctx context.Context) error { return nil })
+
+	status := func() drainStatus {
+		rec := httptest.NewRecorder()
+		p.DrainStatusHandler(rec, httptest.NewRequest(http.MethodGet, "/drain/status", nil))
+		var s drainStatus
+		if err := json.Unmarshal(rec.Body.Bytes(), &s); err != nil {
+			t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+		}
+		return s
+	}
+
+	if s := status(); len(s.CallbacksPending) != 2 {
+		t.Errorf("callbacksPending before the shutdown = %v, want [db notify]", s.CallbacksPending)
+	}
+
+	// notify doesn't run for a programmatic shutdown.
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+	if s := status(); len(s.CallbacksPending) != 0 || s.Phase != "done" {
+		t.Errorf("status after the shutdown = %+v, want none pending and done", s)
+	}
+}
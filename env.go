@@ -0,0 +1,93 @@
+package exitplan
+
+import (
+	"log"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Environment variables read by NewPlanFromEnv.
+const (
+	EnvGradePeriod = "EXITPLAN_GRACE_PERIOD"
+	EnvTimeout     = "EXITPLAN_TIMEOUT"
+	EnvSignals     = "EXITPLAN_SIGNALS"
+)
+
+// signalNames are the names recognized in EXITPLAN_SIGNALS.
+var signalNames = map[string]os.Signal{
+	"SIGINT":  syscall.SIGINT,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGQUIT": syscall.SIGQUIT,
+}
+
+// NewPlanFromEnv will create a new ExecutionPlan like NewPlan, using the
+//  environment to override the defaults:
+//
+//   EXITPLAN_GRACE_PERIOD  GradePeriod, parsed with time.ParseDuration (e.g. "5s")
+//   EXITPLAN_TIMEOUT       Timeout, parsed with time.ParseDuration (e.g. "20s")
+//   EXITPLAN_SIGNALS       Comma separated Signals (e.g. "SIGINT,SIGTERM")
+//
+// The recognized signal names are SIGINT (syscall.SIGINT), SIGTERM (syscall.SIGTERM),
+//  SIGHUP (syscall.SIGHUP) and SIGQUIT (syscall.SIGQUIT). They are case-insensitive
+//  and the "SIG" prefix is optional. Unset values keep the default, invalid values
+//  are logged and the default is kept.
+func NewPlanFromEnv() *ExecutionPlan {
+	plan := NewPlan()
+
+	if d, ok := durationFromEnv(EnvGradePeriod); ok {
+		plan.GradePeriod = d
+	}
+	if d, ok := durationFromEnv(EnvTimeout); ok {
+		plan.Timeout = d
+	}
+	if sigs, ok := signalsFromEnv(EnvSignals); ok {
+		plan.Signals = sigs
+	}
+
+	return plan
+}
+
+func durationFromEnv(key string) (time.Duration, bool) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return 0, false
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		log.Printf("exitplan: ignoring invalid %s=%q, using the default", key, value)
+		return 0, false
+	}
+	return d, true
+}
+
+func signalsFromEnv(key string) ([]os.Signal, bool) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil, false
+	}
+
+	var sigs []os.Signal
+	for _, name := range strings.Split(value, ",") {
+		sig, ok := parseSignal(name)
+		if !ok {
+			log.Printf("exitplan: ignoring invalid %s=%q, unknown signal %q, using the default", key, value, name)
+			return nil, false
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs, true
+}
+
+// parseSignal returns the signal for name, see NewPlanFromEnv for the recognized names.
+func parseSignal(name string) (os.Signal, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig, ok := signalNames[name]
+	return sig, ok
+}
@@ -0,0 +1,40 @@
+package exitplan
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ShutdownError is the aggregated error of a shutdown where one or more
+//  exit operations have failed.
+type ShutdownError struct {
+	// Errors is keyed by the name of the failed callback, "final" for the final callback.
+	Errors map[string]error
+}
+
+func (e *ShutdownError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s: %s", name, e.Errors[name])
+	}
+	return fmt.Sprintf("exitplan: %d exit operation(s) failed: %s", len(names), strings.Join(parts, "; "))
+}
+
+// Is reports if any of the Errors is target, so errors.Is(plan.Err(), ErrAbandoned)
+//  tells if a callback was abandoned.
+func (e *ShutdownError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package exitplan
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestShutdownErrorIs(t *testing.T) {
+	err := error(&ShutdownError{Errors: map[string]error{
+		"db":    ErrAbandoned,
+		"cache": errors.New("unavailable"),
+	}})
+
+	if !errors.Is(err, ErrAbandoned) {
+		t.Errorf("errors.Is(%v, ErrAbandoned) = false, want true", err)
+	}
+	if errors.Is(err, context.Canceled) {
+		t.Errorf("errors.Is(%v, context.Canceled) = true, want false", err)
+	}
+}
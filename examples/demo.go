@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"time"
 
@@ -22,10 +23,11 @@ func main() {
 	}
 
 	plan := exitplan.NewPlan()
-	plan.Add("http", srv.Shutdown)
 
-	// Register a Request Handler on "/readyz" for the status.
-	m.HandleFunc("/readyz", plan.HandlerFunc).Methods(http.MethodGet)
+	// Register the shutdown of srv, and the Request Handlers on "/readyz" and "/healthz" for the status.
+	plan.ManageServerWithProbes("http", srv, exitplan.ProbeMuxFunc(func(pattern string, h func(http.ResponseWriter, *http.Request)) {
+		m.HandleFunc(pattern, h).Methods(http.MethodGet, http.MethodHead)
+	}))
 
 	go srv.ListenAndServe()
 
@@ -37,6 +39,9 @@ func main() {
 		fmt.Println("final callback made")
 		return nil
 	})
-	plan.Wait(context.TODO())
+	if err := plan.Wait(context.TODO()); err != nil {
+		// Exit with a non-zero code when the cleanup has failed
+		log.Fatal(err)
+	}
 
 }
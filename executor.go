@@ -0,0 +1,20 @@
+package exitplan
+
+// Executor runs the funcs submitted by the plan, like the concurrent callbacks.
+// Submit may block, e.g. until a bounded pool has a free worker.
+type Executor interface {
+	Submit(f func())
+}
+
+// goExecutor is the Executor running each func in its own goroutine.
+type goExecutor struct{}
+
+func (goExecutor) Submit(f func()) { go f() }
+
+// executor returns the Executor of the plan, goroutines when it's nil.
+func (p *ExecutionPlan) executor() Executor {
+	if p.Executor == nil {
+		return goExecutor{}
+	}
+	return p.Executor
+}
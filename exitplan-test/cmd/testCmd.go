@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -15,12 +16,16 @@ import (
 var (
 	healthChecks     bool
 	httpChecksServer int64
+	gradePeriod      time.Duration
+	failCleanup      bool
 
 	testCmd = &cobra.Command{
 		Use:   "test",
 		Short: "Test the usage of the signals.",
 		Long:  "Runs the code as normal in conjunction with cobra Command with the usage of SIGINT and SIGTERM.",
-		Run: func(cmd *cobra.Command, args []string) {
+		// A failed shutdown is not a usage error.
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
 
 			m := mux.NewRouter()
 
@@ -32,8 +37,12 @@ var (
 			}
 
 			plan := exitplan.NewPlan()
-			plan.GradePeriod = 5 * time.Second
-			plan.Add("http", srv.Shutdown)
+			plan.GradePeriod = gradePeriod
+			if failCleanup {
+				plan.Add("failing", func(ctx context.Context) error {
+					return errors.New("cleanup failed on purpose")
+				})
+			}
 			//plan.AddMany(map[string]exitplan.ExitOperation{
 			//	"http": func(ctx context.Context) error {
 			//		// Delayed Shutdown
@@ -42,9 +51,11 @@ var (
 			//	},
 			//})
 
-			// Register a Request Handler on "/readyz" for the status.
+			// Register the shutdown of srv, and the Request Handlers on "/readyz" and "/healthz" for the status.
 			// See https://kubernetes.io/docs/reference/using-api/health-checks/ for more information
-			m.HandleFunc("/readyz", plan.HandlerFunc).Methods(http.MethodGet)
+			plan.ManageServerWithProbes("http", srv, exitplan.ProbeMuxFunc(func(pattern string, h func(http.ResponseWriter, *http.Request)) {
+				m.HandleFunc(pattern, h).Methods(http.MethodGet, http.MethodHead)
+			}))
 
 			go srv.ListenAndServe()
 
@@ -56,8 +67,9 @@ var (
 				fmt.Println("final callback made")
 				return nil
 			})
-			plan.Wait(context.TODO())
 
+			// Return the shutdown error so the exit code reflects failed cleanups.
+			return plan.Wait(context.TODO())
 		},
 	}
 )
@@ -65,4 +77,6 @@ var (
 func init() {
 	testCmd.Flags().BoolVarP(&healthChecks, "heath-checks", "e", true, "enable health checks server.")
 	testCmd.Flags().Int64VarP(&httpChecksServer, "port", "p", 8855, "http port for the test server to run on.")
+	testCmd.Flags().DurationVar(&gradePeriod, "grade-period", 5*time.Second, "time given to connections to drain.")
+	testCmd.Flags().BoolVar(&failCleanup, "fail-cleanup", false, "register a failing callback, to check the exit code.")
 }
@@ -0,0 +1,76 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// TestMain will run main instead of the tests when re-executed by runMain.
+func TestMain(m *testing.M) {
+	if args := os.Getenv("EXITPLAN_TEST_MAIN"); args != "" {
+		os.Args = append([]string{"exitplan"}, strings.Fields(args)...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain will run main with args in a child process, sending it SIGTERM once
+//  the plan is watching the signals, and return the exit code.
+func runMain(t *testing.T, args string) int {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), "EXITPLAN_TEST_MAIN="+args)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The plan logs once the signals are watched by Wait.
+	lines := bufio.NewScanner(stderr)
+	for lines.Scan() && !strings.Contains(lines.Text(), "watching the signals") {
+	}
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	for lines.Scan() {
+	}
+
+	var exitErr *exec.ExitError
+	if err := cmd.Wait(); errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return 0
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+		code int
+	}{
+		{"clean shutdown", "test --port 0 --grade-period 0", 0},
+		{"failed cleanup", "test --port 0 --grade-period 0 --fail-cleanup", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := runMain(t, tt.args); code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package exitplantest_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"github.com/nhalstead/exitplan"
+	"github.com/nhalstead/exitplan/exitplantest"
+)
+
+// newPlan returns a plan on clock that doesn't log nor exit the process.
+func newPlan(clock *exitplan.FakeClock, timeout time.Duration) *exitplan.ExecutionPlan {
+	plan := exitplan.NewPlanWithSignals(0, timeout)
+	plan.Clock = clock
+	plan.DisableForceExit = true
+	plan.Logger = log.New(ioutil.Discard, "", 0)
+	return plan
+}
+
+func ExampleRunAndTrigger() {
+	clock := exitplan.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	plan := newPlan(clock, time.Second)
+
+	for _, name := range []string{"http", "queue", "db"} {
+		_ = plan.Sequential(name, func(ctx context.Context) error {
+			return nil
+		})
+	}
+
+	run := exitplantest.RunAndTrigger(plan, nil)
+	fmt.Println(run.Callbacks())
+	fmt.Println(run.States())
+	// Output:
+	// [http queue db]
+	// [draining disposing finalizing done]
+}
+
+func ExampleRunAndTrigger_timeout() {
+	clock := exitplan.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	plan := newPlan(clock, time.Second)
+
+	release := make(chan struct{})
+	defer close(release)
+	_ = plan.Add("hung", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	// Move the clock past the Timeout while the shutdown waits on it.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+				if clock.Pending() > 0 {
+					clock.Advance(100 * time.Millisecond)
+				}
+			}
+		}
+	}()
+
+	run := exitplantest.RunAndTrigger(plan, nil)
+	fmt.Println(run.Result.TimedOut)
+	fmt.Println(run.Result.Errors["hung"])
+	// Output:
+	// true
+	// exitplan: callback abandoned after its deadline
+}
@@ -0,0 +1,107 @@
+// Package exitplantest provides helpers to test the shutdown of an exitplan.ExecutionPlan,
+//  recording the timeline of what has happened during it for assertions.
+package exitplantest
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nhalstead/exitplan"
+)
+
+// Event is something that has happened during the shutdown, either the plan moving
+//  to a State or a callback completing.
+type Event struct {
+	// Time is the time of the event, from the Clock of the plan (if any).
+	Time time.Time
+	// State is the State the plan has moved to, empty for a callback.
+	State string
+	// Callback is the name of the callback that has completed, empty for a State.
+	Callback string
+	// Err is the error of the callback.
+	Err error
+}
+
+// Run is the recording of a shutdown made by RunAndTrigger.
+type Run struct {
+	// Events are in the order they were recorded, the States and the callbacks are
+	//  each in order but may interleave a little differently than they happened.
+	Events []Event
+	// Err is the aggregated error of the shutdown, see ExecutionPlan.Err.
+	Err error
+	// Result is the Result of the shutdown.
+	Result exitplan.Result
+}
+
+// Callbacks returns the names of the callbacks in the order they have completed.
+func (r Run) Callbacks() []string {
+	var names []string
+	for _, e := range r.Events {
+		if e.Callback != "" {
+			names = append(names, e.Callback)
+		}
+	}
+	return names
+}
+
+// States returns the States the plan has moved to, in order.
+func (r Run) States() []string {
+	var states []string
+	for _, e := range r.Events {
+		if e.State != "" {
+			states = append(states, e.State)
+		}
+	}
+	return states
+}
+
+// RunAndTrigger will trigger the shutdown of plan as if sig was received, nil for
+//  a programmatic shutdown, and record its timeline until it has completed.
+// The plan should not be started, use a FakeClock as its Clock to control the
+//  GradePeriod and Timeout, and set DisableForceExit so a Timeout doesn't exit the
+//  test binary.
+func RunAndTrigger(plan *exitplan.ExecutionPlan, sig os.Signal) Run {
+	now := time.Now
+	if plan.Clock != nil {
+		now = plan.Clock.Now
+	}
+
+	var (
+		mu     sync.Mutex
+		events []Event
+		wg     sync.WaitGroup
+	)
+	add := func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		e.Time = now()
+		events = append(events, e)
+	}
+
+	states := plan.StateChanges()
+	progress := plan.Progress()
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for s := range states {
+			add(Event{State: s.String()})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for r := range progress {
+			add(Event{Callback: r.Name, Err: r.Err})
+		}
+	}()
+
+	err := plan.Trigger(context.Background(), sig)
+	wg.Wait()
+
+	return Run{
+		Events: events,
+		Err:    err,
+		Result: plan.Result(),
+	}
+}
@@ -0,0 +1,36 @@
+package exitplan
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+)
+
+// expvarLock guards the check and publish of the names by PublishExpvar.
+var expvarLock sync.Mutex
+
+// PublishExpvar will publish the terminating flag and the current State of the plan
+//  with expvar as "<prefix>.terminating" and "<prefix>.phase", e.g. to be read from
+//  /debug/vars. An error is returned if either name is already published.
+func (p *ExecutionPlan) PublishExpvar(prefix string) error {
+	terminating := prefix + ".terminating"
+	phase := prefix + ".phase"
+
+	expvarLock.Lock()
+	defer expvarLock.Unlock()
+
+	for _, name := range []string{terminating, phase} {
+		if expvar.Get(name) != nil {
+			return fmt.Errorf("exitplan: expvar %q is already published", name)
+		}
+	}
+
+	expvar.Publish(terminating, expvar.Func(func() interface{} {
+		return p.IsTerminating()
+	}))
+	expvar.Publish(phase, expvar.Func(func() interface{} {
+		return p.State().String()
+	}))
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package exitplan
+
+import (
+	"path"
+	"reflect"
+	"runtime"
+	"strconv"
+)
+
+// AddFunc will register the handler like Add, under a name derived from the function
+//  name of handler (e.g. "main.main.func1" for a closure, "sql.(*DB).Close-fm" for a
+//  method value). A numeric suffix is appended when the name is already registered.
+// The name that was used is returned.
+func (p *ExecutionPlan) AddFunc(handler ExitOperation) (string, error) {
+	base := funcName(handler)
+
+	p.callbacksMutex.Lock()
+	defer p.callbacksMutex.Unlock()
+
+	name := base
+	for i := 2; ; i++ {
+		if _, exists := p.callbacks[name]; !exists {
+			break
+		}
+		name = base + "-" + strconv.Itoa(i)
+	}
+
+	return name, p.registerLocked(&callback{
+		name: name,
+		op:   handler,
+	})
+}
+
+// funcName returns the name of the function fn without its package path.
+func funcName(fn interface{}) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "func"
+	}
+	return path.Base(f.Name())
+}
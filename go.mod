@@ -5,4 +5,6 @@ go 1.14
 require (
 	github.com/gorilla/mux v1.8.0
 	github.com/spf13/cobra v1.1.3
+	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,109 @@
+package exitplan
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// HandlerFunc is used on the HTTP Server Side to support a RESTful way of ready state.
+// See https://kubernetes.io/docs/reference/using-api/health-checks/ for more information
+// HEAD requests get the same status code without the body.
+// Once terminating it keeps reporting ready for the ReadinessFailDelay.
+// When any of the checks registered with AddHealthCheck fails the status is 503, the
+//  body being "unhealthy: <names>". Terminating overrides it.
+// With RequireStarted the status is 503 "not started" until Start is called.
+// While the plan is marked degraded the response has the "X-Health: degraded" header
+//  and the DegradedStatusCode, the body being "degraded: <reason>".
+func (p *ExecutionPlan) HandlerFunc(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	status, body := http.StatusOK, "ok"
+	if p.IsTerminating() && p.terminatingFor() >= p.ReadinessFailDelay {
+		status, body = http.StatusServiceUnavailable, "terminating"
+		p.probedOnce.Do(func() {
+			close(p.probed)
+		})
+		p.probedTerminating()
+	} else if p.RequireStarted && atomic.LoadInt32(&p.started) == 0 {
+		status, body = http.StatusServiceUnavailable, "not started"
+	} else if failing := p.failingHealthChecks(r.Context()); len(failing) > 0 {
+		status, body = http.StatusServiceUnavailable, "unhealthy: "+strings.Join(failing, ", ")
+	} else if reason, ok := p.Degraded(); ok {
+		w.Header().Set("X-Health", "degraded")
+		status, body = http.StatusOK, "degraded: "+reason
+		if p.DegradedStatusCode != 0 {
+			status = p.DegradedStatusCode
+		}
+	}
+
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+// LivenessHandler is used for a liveness probe, as "/healthz". It reports "ok" for as
+//  long as the process serves requests, terminating included, so the orchestrator
+//  doesn't restart a process that's shutting down gracefully.
+func (p *ExecutionPlan) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write([]byte("ok"))
+	}
+}
+
+// MarkDegraded will mark the plan as degraded for reason, still serving but with
+//  a dependency in trouble so a load balancer may prefer other replicas.
+func (p *ExecutionPlan) MarkDegraded(reason string) {
+	p.degradedMutex.Lock()
+	defer p.degradedMutex.Unlock()
+	p.degraded = true
+	p.degradedReason = reason
+}
+
+// ClearDegraded will clear the degraded mark set by MarkDegraded.
+func (p *ExecutionPlan) ClearDegraded() {
+	p.degradedMutex.Lock()
+	defer p.degradedMutex.Unlock()
+	p.degraded = false
+	p.degradedReason = ""
+}
+
+// Degraded returns the reason given to MarkDegraded and if the plan is degraded.
+func (p *ExecutionPlan) Degraded() (string, bool) {
+	p.degradedMutex.RLock()
+	defer p.degradedMutex.RUnlock()
+	return p.degradedReason, p.degraded
+}
+
+// AddHealthCheck will register check under name, run by HandlerFunc for each request
+//  while the plan isn't terminating. It reports 503 when check returns an error, so
+//  the one handler aggregates the health of the process.
+func (p *ExecutionPlan) AddHealthCheck(name string, check func(ctx context.Context) error) {
+	p.healthMutex.Lock()
+	defer p.healthMutex.Unlock()
+
+	if p.healthChecks == nil {
+		p.healthChecks = make(map[string]func(ctx context.Context) error)
+	}
+	p.healthChecks[name] = check
+}
+
+// failingHealthChecks returns the names of the health checks failing, sorted.
+func (p *ExecutionPlan) failingHealthChecks(ctx context.Context) []string {
+	p.healthMutex.RLock()
+	defer p.healthMutex.RUnlock()
+
+	var failing []string
+	for name, check := range p.healthChecks {
+		if err := check(ctx); err != nil {
+			failing = append(failing, name)
+		}
+	}
+	sort.Strings(failing)
+	return failing
+}
@@ -0,0 +1,44 @@
+package exitplan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandlerFunc(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		terminating bool
+		status      int
+		body        string
+	}{
+		{"get ready", http.MethodGet, false, http.StatusOK, "ok"},
+		{"head ready", http.MethodHead, false, http.StatusOK, ""},
+		{"get terminating", http.MethodGet, true, http.StatusServiceUnavailable, "terminating"},
+		{"head terminating", http.MethodHead, true, http.StatusServiceUnavailable, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPlanWithSignals(time.Second, 2*time.Second)
+			if tt.terminating {
+				p.MarkTerminating()
+			}
+
+			w := httptest.NewRecorder()
+			p.HandlerFunc(w, httptest.NewRequest(tt.method, "/readyz", nil))
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+				t.Errorf("Content-Type = %q, want %q", ct, "text/plain; charset=utf-8")
+			}
+			if w.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package exitplan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// RunWorker will start work in a new goroutine with a context that's canceled once
+//  the plan is terminating, and register a callback under name that waits for work
+//  to return. The error returned by work is the error of the callback.
+// The worker is not started if the callback can't be registered.
+func (p *ExecutionPlan) RunWorker(name string, work func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	err := p.Add(name, func(ctx context.Context) error {
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	exit := p.NewExitChan()
+
+	go func() {
+		<-exit
+		cancel()
+	}()
+	go func() {
+		done <- work(ctx)
+	}()
+
+	return nil
+}
+
+// AddProcess will register a callback under name that sends sig to the child process
+//  of cmd and waits up to wait for it to exit, killing it when it doesn't.
+// The plan takes over waiting for the process, cmd.Wait must not be called elsewhere.
+// A child exiting with a non-zero status (as commonly happens on sig) is not an error.
+func (p *ExecutionPlan) AddProcess(name string, cmd *exec.Cmd, sig os.Signal, wait time.Duration) error {
+	return p.Add(name, func(ctx context.Context) error {
+		if cmd.Process == nil {
+			// Never started, nothing to stop.
+			return nil
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- cmd.Wait()
+		}()
+
+		if err := cmd.Process.Signal(sig); err != nil {
+			p.warnf("%s: failed to signal process %d: %s", name, cmd.Process.Pid, err.Error())
+		}
+
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case err := <-done:
+			return processExitError(err)
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+
+		p.warnf("%s: process %d did not exit, killing it", name, cmd.Process.Pid)
+		if err := cmd.Process.Kill(); err != nil {
+			return err
+		}
+		<-done
+		return fmt.Errorf("process %d did not exit within %s and was killed", cmd.Process.Pid, wait)
+	})
+}
+
+// processExitError ignores the error of a process that has exited with a non-zero status.
+func processExitError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil
+	}
+	return err
+}
+
+// AddPlan will register the child plan under name, running its whole shutdown
+//  sequence (drain, callbacks and final callback) as a callback of this plan.
+// The child is bound by the deadline of the callbacks, its GradePeriod and Timeout
+//  are shrunk to fit within it, and its aggregated error is the error of the callback.
+// The child plan should not be started itself. The child takes the DisableForceExit
+//  of this plan while it runs, so its Timeout can't exit the process of an embedder.
+func (p *ExecutionPlan) AddPlan(name string, child *ExecutionPlan) error {
+	return p.Add(name, func(ctx context.Context) error {
+		if deadline := p.callbacksDeadline(); !deadline.IsZero() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+
+		if p.DisableForceExit {
+			prev := child.DisableForceExit
+			child.DisableForceExit = true
+			defer func() {
+				child.DisableForceExit = prev
+			}()
+		}
+		return child.Shutdown(ctx)
+	})
+}
+
+// AfterTerminating will call f in its own goroutine once the plan is terminating,
+//  like context.AfterFunc does for a context. Calling the returned stop func stops f
+//  from being called, it returns false if f has already been started.
+func (p *ExecutionPlan) AfterTerminating(f func()) (stop func() bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := p.NewExitChanCtx(ctx)
+
+	var once sync.Once
+	go func() {
+		select {
+		case <-c:
+			once.Do(func() {
+				go f()
+			})
+		case <-ctx.Done():
+		}
+	}()
+
+	return func() bool {
+		stopped := false
+		once.Do(func() {
+			stopped = true
+			cancel()
+		})
+		return stopped
+	}
+}
+
+// AwaitBarrier will register a sequential callback under name that waits for barrier
+//  to be closed, holding back the sequential callbacks registered after it. This lets
+//  an operator release the stages of a coordinated shutdown by closing chans.
+// The wait is bound by the deadline of the callbacks, ErrAbandoned is its error
+//  when the barrier isn't closed in time.
+func (p *ExecutionPlan) AwaitBarrier(name string, barrier <-chan struct{}) error {
+	return p.Sequential(name, func(ctx context.Context) error {
+		select {
+		case <-barrier:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// AddCloser will register a callback under name that closes c, like a *sql.DB
+//  or a net.Conn, removing the need for a closure around Close.
+func (p *ExecutionPlan) AddCloser(name string, c io.Closer) error {
+	return p.Add(name, func(ctx context.Context) error {
+		return c.Close()
+	})
+}
+
+// CloserCtx is implemented by what is closed with a context, see AddCloserCtx.
+type CloserCtx interface {
+	Close(ctx context.Context) error
+}
+
+// AddCloserCtx is like AddCloser, for what is closed with a context.
+func (p *ExecutionPlan) AddCloserCtx(name string, c CloserCtx) error {
+	return p.Add(name, c.Close)
+}
+
+// AddWaitGroup will register a callback under name that calls stop, to signal the
+//  workers of a pool to return, then waits for wg to reach zero. A nil stop is fine
+//  when the workers already watch the plan, e.g. with NewExitChan.
+// The wait is bound by the deadline of the callbacks, ErrAbandoned is its error
+//  when the workers don't return in time.
+func (p *ExecutionPlan) AddWaitGroup(name string, wg *sync.WaitGroup, stop func()) error {
+	return p.Add(name, func(ctx context.Context) error {
+		if stop != nil {
+			stop()
+		}
+
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// AddConsumer will register a callback under name for the two-phase shutdown of a
+//  message queue consumer (Kafka, RabbitMQ, NATS, ...): stop is called once the plan
+//  is terminating, so no new messages are taken while draining, and drain is called
+//  as the callback to wait for the in-flight messages to be acked and close.
+// stop is called before drain in every case, even with no GradePeriod.
+func (p *ExecutionPlan) AddConsumer(name string, stop func(), drain ExitOperation) error {
+	var once sync.Once
+	err := p.Add(name, func(ctx context.Context) error {
+		once.Do(stop)
+		return drain(ctx)
+	})
+	if err != nil {
+		return err
+	}
+
+	p.AfterTerminating(func() {
+		once.Do(stop)
+	})
+	return nil
+}
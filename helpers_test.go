@@ -0,0 +1,33 @@
+package exitplan
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAddPlanTakesDisableForceExit(t *testing.T) {
+	parent, clock := newTestPlan(0, 10*time.Second)
+	child := NewPlanWithSignals(0, time.Second)
+	child.Clock = clock
+	child.Logger = parent.Logger
+
+	release := make(chan struct{})
+	defer close(release)
+	_ = child.Add("hung", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	_ = parent.AddPlan("child", child)
+
+	// The Timeout of the child elapses, the test binary would exit if it force exits.
+	_ = shutdownAndAdvance(t, parent, clock, 100*time.Millisecond)
+
+	if err := child.Result().Errors["hung"]; !errors.Is(err, ErrAbandoned) {
+		t.Errorf("error of hung = %v, want %v", err, ErrAbandoned)
+	}
+	if child.DisableForceExit {
+		t.Error("DisableForceExit of the child was not restored")
+	}
+}
@@ -0,0 +1,23 @@
+package exitplan
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	jitterRand  = rand.New(rand.NewSource(time.Now().UnixNano()))
+	jitterMutex sync.Mutex
+)
+
+// jitter returns a random duration in [0, max), zero when max isn't positive.
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+
+	jitterMutex.Lock()
+	defer jitterMutex.Unlock()
+	return time.Duration(jitterRand.Int63n(int64(max)))
+}
@@ -0,0 +1,11 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package exitplan
+
+import (
+	"time"
+)
+
+// escalateKill is not supported on this platform, KillOnTimeout has no effect.
+func escalateKill(d time.Duration) {}
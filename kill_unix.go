@@ -0,0 +1,17 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package exitplan
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// escalateKill will send SIGKILL to the process itself if it's still alive after d.
+func escalateKill(d time.Duration) {
+	time.AfterFunc(d, func() {
+		_ = syscall.Kill(os.Getpid(), syscall.SIGKILL)
+	})
+}
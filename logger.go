@@ -0,0 +1,118 @@
+package exitplan
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Logger is used by the plan to log the progress of the shutdown.
+// A *log.Logger can be used.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// LogLevel is the severity of a log line of the plan, see ExecutionPlan.LogLevel.
+type LogLevel int
+
+const (
+	// LevelDebug is for the progress of each callback, like "disposing".
+	LevelDebug LogLevel = iota
+	// LevelInfo is for the progress of the shutdown.
+	LevelInfo
+	// LevelWarn is for what may need tuning, like abandoned callbacks and retries.
+	LevelWarn
+	// LevelError is for failed callbacks and forced exits.
+	LevelError
+)
+
+func (p *ExecutionPlan) debugf(format string, v ...interface{}) {
+	p.logAt(LevelDebug, format, v...)
+}
+
+// logf will log at LevelInfo.
+func (p *ExecutionPlan) logf(format string, v ...interface{}) {
+	p.logAt(LevelInfo, format, v...)
+}
+
+func (p *ExecutionPlan) warnf(format string, v ...interface{}) {
+	p.logAt(LevelWarn, format, v...)
+}
+
+func (p *ExecutionPlan) errorf(format string, v ...interface{}) {
+	p.logAt(LevelError, format, v...)
+}
+
+// logAt will log when level is at least the LogLevel of the plan.
+func (p *ExecutionPlan) logAt(level LogLevel, format string, v ...interface{}) {
+	if level < p.LogLevel {
+		return
+	}
+	p.print(format, v...)
+}
+
+// print will log with the Logger of the plan, the standard logger when it's nil.
+func (p *ExecutionPlan) print(format string, v ...interface{}) {
+	if id := p.correlationID(); id != "" {
+		format = "[" + id + "] " + format
+	}
+	if p.Logger == nil {
+		log.Printf(format, v...)
+		return
+	}
+	p.Logger.Printf(format, v...)
+}
+
+// logAt will log to log when level is at least the LogLevel of the shutdown.
+func (s *shutdown) logAt(log Logger, level LogLevel, format string, v ...interface{}) {
+	if level < s.level {
+		return
+	}
+	log.Printf(format, v...)
+}
+
+// bufferedLogger holds the lines of a callback to log them as one group,
+//  see GroupLogs. Lines are passed through once the group has been flushed.
+type bufferedLogger struct {
+	out Logger
+	mu  *sync.Mutex
+
+	lines   []string
+	flushed bool
+	lock    sync.Mutex
+}
+
+func (b *bufferedLogger) Printf(format string, v ...interface{}) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.flushed {
+		b.mu.Lock()
+		b.out.Printf(format, v...)
+		b.mu.Unlock()
+		return
+	}
+	b.lines = append(b.lines, fmt.Sprintf(format, v...))
+}
+
+// flush will log the buffered lines, holding mu so groups don't interleave.
+func (b *bufferedLogger) flush() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.mu.Lock()
+	for _, line := range b.lines {
+		b.out.Printf("%s", line)
+	}
+	b.mu.Unlock()
+
+	b.lines = nil
+	b.flushed = true
+}
+
+// loggerFunc adapts a function to the Logger interface.
+type loggerFunc func(format string, v ...interface{})
+
+func (f loggerFunc) Printf(format string, v ...interface{}) {
+	f(format, v...)
+}
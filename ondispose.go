@@ -0,0 +1,53 @@
+package exitplan
+
+import (
+	"context"
+	"fmt"
+)
+
+type disposeKey struct{}
+
+// OnDispose will enqueue f on the plan running the callback of ctx, for cleanup that's
+//  discovered during the shutdown itself (e.g. connections opened by a callback).
+// The enqueued funcs run concurrently once the callbacks have completed, before the
+//  final callback, and are bound by the same Timeout. It returns false when ctx
+//  isn't the ctx of a callback.
+func OnDispose(ctx context.Context, f func() error) bool {
+	p, ok := ctx.Value(disposeKey{}).(*ExecutionPlan)
+	if !ok {
+		return false
+	}
+
+	p.disposeMutex.Lock()
+	defer p.disposeMutex.Unlock()
+	p.disposeQueue = append(p.disposeQueue, f)
+	return true
+}
+
+// runOnDispose will run the funcs enqueued with OnDispose until none are left,
+//  as they may enqueue more themselves.
+func (p *ExecutionPlan) runOnDispose(run *shutdown) {
+	for n := 0; ; {
+		p.disposeMutex.Lock()
+		queue := p.disposeQueue
+		p.disposeQueue = nil
+		p.disposeMutex.Unlock()
+
+		if len(queue) == 0 {
+			return
+		}
+
+		callbacks := make([]*callback, len(queue))
+		for i, f := range queue {
+			n++
+			f := f
+			callbacks[i] = &callback{
+				name: fmt.Sprintf("on-dispose-%d", n),
+				op: func(ctx context.Context) error {
+					return f()
+				},
+			}
+		}
+		run.runConcurrent(callbacks)
+	}
+}
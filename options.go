@@ -0,0 +1,55 @@
+package exitplan
+
+import (
+	"os"
+	"syscall"
+)
+
+// HUPReloadByDefault will make NewPlan and NewPlanWithTimer treat SIGHUP as a reload
+//  signal instead of a terminating one, as is common for daemons. See WithHUPReload.
+// It's false by default, SIGHUP terminates like SIGINT and SIGTERM.
+var HUPReloadByDefault = false
+
+// PlanOption configures an ExecutionPlan when it's created.
+type PlanOption func(p *ExecutionPlan)
+
+// WithHUPReload will move SIGHUP out of the terminating Signals and into the
+//  ReloadSignals, calling handler each time it's received.
+func WithHUPReload(handler func()) PlanOption {
+	return func(p *ExecutionPlan) {
+		hupReload(p)
+		p.ReloadHandler = handler
+	}
+}
+
+// hupReload moves SIGHUP from the Signals to the ReloadSignals of p.
+func hupReload(p *ExecutionPlan) {
+	sigs := make([]os.Signal, 0, len(p.Signals))
+	for _, sig := range p.Signals {
+		if sig != syscall.SIGHUP {
+			sigs = append(sigs, sig)
+		}
+	}
+	p.Signals = sigs
+
+	if !containsSignal(p.ReloadSignals, syscall.SIGHUP) {
+		p.ReloadSignals = append(p.ReloadSignals, syscall.SIGHUP)
+	}
+}
+
+// observeSignal will call the OnNonTerminatingSignal hook for sig.
+func (p *ExecutionPlan) observeSignal(sig os.Signal) {
+	if p.OnNonTerminatingSignal != nil {
+		go p.OnNonTerminatingSignal(sig)
+	}
+}
+
+// reload will call the ReloadHandler for sig.
+func (p *ExecutionPlan) reload(sig os.Signal) {
+	if p.ReloadHandler == nil {
+		p.warnf("%s received, no reload handler", sig)
+		return
+	}
+	p.logf("%s received, reloading", sig)
+	go p.ReloadHandler()
+}
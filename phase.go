@@ -0,0 +1,46 @@
+package exitplan
+
+import (
+	"fmt"
+)
+
+// Phase is the point of the shutdown a callback registered with AddAt runs at.
+type Phase int
+
+const (
+	// PostDrain is once the GradePeriod has elapsed, like Add. It's the default.
+	PostDrain Phase = iota
+	// PreDrain is before the GradePeriod begins, like AddPreStop.
+	PreDrain
+	// Final is once every other callback has completed, like Finally.
+	Final
+)
+
+var phaseNames = map[Phase]string{
+	PostDrain: "post-drain",
+	PreDrain:  "pre-drain",
+	Final:     "final",
+}
+
+func (ph Phase) String() string {
+	if name, ok := phaseNames[ph]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// AddAt will register the handler under name to run at phase of the shutdown.
+// There's a single final callback, Final replaces it as Finally does and it's
+//  reported as "final" in the Result rather than under name.
+func (p *ExecutionPlan) AddAt(phase Phase, name string, handler ExitOperation) error {
+	switch phase {
+	case PostDrain:
+		return p.Add(name, handler)
+	case PreDrain:
+		return p.AddPreStop(name, handler)
+	case Final:
+		p.Finally(handler)
+		return nil
+	}
+	return fmt.Errorf("exitplan: unknown phase %d for %q", int(phase), name)
+}
@@ -2,72 +2,316 @@ package exitplan
 
 import (
 	"context"
-	"log"
-	"net/http"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
 
+// ErrTooManyCallbacks is returned when registering a callback would exceed MaxCallbacks.
+var ErrTooManyCallbacks = errors.New("exitplan: too many callbacks")
+
+// MustRunBudget is the time given to the callbacks registered with AddMustRun
+//  when they run before the forced exit of the Timeout.
+var MustRunBudget = 500 * time.Millisecond
+
 // ExitOperation is a cleanup function on shutting down
 type ExitOperation func(ctx context.Context) error
 
 type ExecutionPlan struct {
-	Signals            []os.Signal
-	Timeout            time.Duration
-	GradePeriod        time.Duration
+	// Accessed atomically, first to keep them 64-bit aligned on 32-bit platforms.
+	inFlight               int64
+	inFlightAfterDrain     int64
+	peakGoroutines         int64
+	drained                int64
+
+	Signals                []os.Signal
+
+	// Timeout and GradePeriod are to be set before Start, use SetTimeout and
+	//  SetGradePeriod to change them once the plan is running.
+	Timeout                time.Duration
+	GradePeriod            time.Duration
+
+	// SequentialFirst will run the callbacks registered with Sequential before the
+	//  concurrent ones registered with Add. By default they run after.
+	SequentialFirst        bool
+
+	// GradePeriodJitter adds a random duration up to it to the GradePeriod, so a fleet
+	//  receiving SIGTERM at once doesn't run its callbacks at the same instant.
+	// Zero keeps the GradePeriod as is.
+	GradePeriodJitter      time.Duration
+
+	// ReverseOrder will run the sequential callbacks in the reverse of the order
+	//  they were registered, closing what was opened last first like defer does.
+	ReverseOrder           bool
+
+	// KillOnTimeout will send SIGKILL to the process itself when it's still alive
+	//  a second after the force exit on timeout, e.g. when a cgo thread is stuck.
+	// Only supported on Unix systems.
+	KillOnTimeout          bool
+
+	// DisableForceExit will not exit the process when the Timeout elapses, for a plan
+	//  embedded in an application managing its own lifecycle. The callbacks still running
+	//  are abandoned instead and the Result is TimedOut. The caller is then responsible
+	//  for killing the process if needed, a hung callback keeps running in the background.
+	DisableForceExit       bool
+
+	// SignalMode is the ShutdownMode of the shutdown triggered by each of the signals,
+	//  e.g. an expedited one for SIGINT from an operator. Signals without one use the
+	//  GradePeriod and Timeout of the plan.
+	SignalMode             map[os.Signal]ShutdownMode
+
+	// SkipDrainSignals are the signals that skip the GradePeriod and go straight to
+	//  the callbacks, e.g. SIGINT for an interactive Ctrl-C. The plan is still marked
+	//  as terminating and the exit chans are still closed.
+	SkipDrainSignals       []os.Signal
+
+	// ReloadSignals are watched along with the Signals, but call the ReloadHandler
+	//  instead of terminating. See WithHUPReload.
+	ReloadSignals          []os.Signal
+	ReloadHandler          func()
+
+	// OnNonTerminatingSignal is called in its own goroutine with each watched signal
+	//  that doesn't terminate: the ReloadSignals, and the ones ignored by the
+	//  FirstSignalAction or SecondSignalAction. It's called along with the ReloadHandler.
+	OnNonTerminatingSignal func(sig os.Signal)
+
+	// FirstSignalAction is the action on the first signal received, Drain by default.
+	FirstSignalAction      SignalAction
+
+	// SecondSignalAction is the action on the second signal received and any after it,
+	//  Ignore by default so the shutdown in progress completes. Set it to ForceExit to
+	//  let an operator escalate with a second Ctrl-C.
+	SecondSignalAction     SignalAction
+
+	// ConfirmOnSignal is asked before draining when stdin is a terminal, returning
+	//  false ignores the signal and keeps listening. A second signal while asking
+	//  skips the question and shuts down. See PromptConfirm for CLI tools.
+	ConfirmOnSignal        func(sig os.Signal) bool
+
+	// Executor runs the concurrent callbacks, a goroutine for each of them when nil.
+	// See the pool package for a bounded one.
+	Executor               Executor
+
+	// Clock is the source of time for the GradePeriod and Timeout, the time package when nil.
+	Clock                  Clock
+
+	// CorrelationID is included in the log lines of the plan and given to the callbacks
+	//  in their ctx, see WithCorrelationID.
+	CorrelationID          string
+
+	// ReadinessFailDelay is how long HandlerFunc keeps reporting ready once the plan
+	//  is terminating, for load balancers that need a probe cycle to complete first,
+	//  or platforms already marking the pod unready on preStop. Only the response is
+	//  delayed, the drain and IsTerminating are not. Zero reports terminating immediately.
+	// It's the readiness settle delay, there's no separate ReadinessSettleDelay setting.
+	ReadinessFailDelay     time.Duration
+
+	// ReadinessDrainedProbes is the number of probes HandlerFunc must report terminating
+	//  to before the hooks of AfterReadinessDrained are called, 2 by default.
+	ReadinessDrainedProbes int
+
+	// AwaitProbe is the longest to wait for HandlerFunc to report terminating to a probe
+	//  before the GradePeriod begins, so none of it is spent before the load balancer
+	//  has noticed. Zero begins the GradePeriod immediately.
+	AwaitProbe             time.Duration
+
+	// RecordOutcome is called once at the end of the shutdown with whether it was clean
+	//  (see CompletedCleanly) and how long it took, false before a forced exit. It's to
+	//  track how often shutdowns are forced, e.g. with a counter of a metrics backend.
+	RecordOutcome          func(clean bool, d time.Duration)
+
+	// GoroutineThreshold enables sampling the number of goroutines during the shutdown,
+	//  warning when it goes above it to spot callbacks leaking goroutines. See Stats
+	//  for the peak. Zero disables it.
+	GoroutineThreshold     int
+
+	// Logger is used to log the progress of the shutdown, the standard logger when nil.
+	Logger                 Logger
+
+	// LogLevel is the lowest level logged, e.g. LevelWarn to only log what went wrong.
+	// All lines are logged by default.
+	LogLevel               LogLevel
+
+	// GroupLogs will buffer the log lines of each callback and log them together once
+	//  it has completed, so concurrent callbacks don't interleave their lines.
+	GroupLogs              bool
+
+	// DegradedStatusCode is the status code of HandlerFunc while the plan is marked
+	//  degraded with MarkDegraded, 200 when zero.
+	DegradedStatusCode     int
+
+	// RequireStarted will make HandlerFunc report 503 "not started" until Start (or
+	//  Wait) is called, so probes don't pass before the shutdown handling is armed.
+	RequireStarted         bool
+
+	// MaxCallbacks is the limit of registered callbacks, registering more returns
+	//  ErrTooManyCallbacks. Zero is unlimited.
+	MaxCallbacks           int
 
-	callbacks          map[string]ExitOperation
-	callbacksMutex     sync.RWMutex
-	finalCallback      ExitOperation
+	// MaxConcurrency is the limit of concurrent callbacks running at once.
+	// Zero is unlimited.
+	MaxConcurrency         int
 
-	isTerminating      bool
-	isTerminatingMutex sync.RWMutex
+	// SuccessThreshold is the number of exit operations that must succeed for the shutdown
+	//  to be reported as successful by CompletedCleanly, Err and Wait. Below 1 it's a fraction
+	//  of them instead (0.8 is 80%), zero requires all of them to succeed.
+	SuccessThreshold       float64
 
-	termListeners      []chan struct{}
-	termLock           sync.Mutex
-	interruptListen    sync.Mutex
+	// RetryNotReadyDelay is the delay before invoking an ExitOperation again
+	//  when it has returned ErrNotReady.
+	RetryNotReadyDelay     time.Duration
+
+	callbacks              map[string]*callback
+	callbackIndex          int
+	callbacksMutex         sync.RWMutex
+	finalCallback          func(ctx context.Context, err error) error
+	finalRetries           int
+	finalBackoff           time.Duration
+
+	isTerminating          bool
+	isTerminatingMutex     sync.RWMutex
+	terminatingSince       time.Time
+	state                  int32
+	started                int32
+	degraded               bool
+	degradedReason         string
+	degradedMutex          sync.RWMutex
+	healthChecks           map[string]func(ctx context.Context) error
+	healthMutex            sync.RWMutex
+	stateListeners         []chan State
+	progressListeners      []chan CallbackResult
+	progressClosed         bool
+	stateLock              sync.Mutex
+
+	termListeners          []chan struct{}
+	doneListeners          []chan struct{}
+	termLock               sync.Mutex
+	interruptListen        sync.Mutex
+
+	signalsMutex           sync.RWMutex
+	signalsChanged         chan struct{}
+
+	preStopOnce            sync.Once
+	terminatingProbes      int32
+	readinessHooks         []func()
+	readinessIsDrained     bool
+	readinessMutex         sync.Mutex
+	tracer                 *tracer
+	drainGauges            []func() int
+	ran                    ranGuard
+	outcomeOnce            sync.Once
+	probed                 chan struct{}
+	probedOnce             sync.Once
+	disposeQueue           []func() error
+	disposeMutex           sync.Mutex
+	correlation            atomic.Value
+	outcome                *outcome
+	triggers               chan triggerEvent
+	dispatchOnce           sync.Once
+	finishOnce             sync.Once
+	shutdownRequested      chan struct{}
+	settingsMutex          sync.RWMutex
+	deadline               time.Time
+	deadlineMutex          sync.RWMutex
+	done                   chan struct{}
+	result                 Result
+	signal                 os.Signal
+	resultMutex            sync.RWMutex
 }
 
 // NewPlan will create a new ExecutionPlan with a default
 //  GradePeriod of 5 seconds and Timeout of 25 seconds
-func NewPlan() *ExecutionPlan {
-	return NewPlanWithTimer(5 * time.Second, 20 * time.Second)
+func NewPlan(opts ...PlanOption) *ExecutionPlan {
+	return NewPlanWithTimer(5 * time.Second, 20 * time.Second, opts...)
 }
 
-func NewPlanWithTimer(gradePeriod, timeout time.Duration) *ExecutionPlan {
+func NewPlanWithTimer(gradePeriod, timeout time.Duration, opts ...PlanOption) *ExecutionPlan {
 	plan := ExecutionPlan{
 		Signals: []os.Signal{
 			syscall.SIGINT,
 			syscall.SIGTERM,
 			syscall.SIGHUP,
 		},
-		Timeout:       timeout,
-		GradePeriod:   gradePeriod,
-		callbacks:     make(map[string]ExitOperation, 5),
-		termListeners: make([]chan struct{}, 0),
-		isTerminating: false,
+		Timeout:                timeout,
+		GradePeriod:            gradePeriod,
+		RetryNotReadyDelay:     500 * time.Millisecond,
+		inFlightAfterDrain:     -1,
+		ReadinessDrainedProbes: 2,
+		SecondSignalAction:     Ignore,
+		callbacks:              make(map[string]*callback, 5),
+		termListeners:          make([]chan struct{}, 0),
+		isTerminating:          false,
+		signalsChanged:         make(chan struct{}, 1),
+		outcome:                newOutcome(),
+		triggers:               make(chan triggerEvent),
+		shutdownRequested:      make(chan struct{}),
+		done:                   make(chan struct{}),
+		probed:                 make(chan struct{}),
+	}
+
+	plan.outcome.onRecord = plan.recorded
+
+	if HUPReloadByDefault {
+		hupReload(&plan)
+	}
+	for _, opt := range opts {
+		opt(&plan)
 	}
 
 	return &plan
 }
 
+// NewPlanWithSignals is like NewPlanWithTimer, but only watches sigs instead of the
+//  default SIGINT, SIGTERM and SIGHUP. Without any the plan is manual-only, it's shut
+//  down with Shutdown (or another trigger like TriggerOnFile).
+// HUPReloadByDefault doesn't apply, sigs are the only signals watched.
+func NewPlanWithSignals(gradePeriod, timeout time.Duration, sigs ...os.Signal) *ExecutionPlan {
+	return NewPlanWithTimer(gradePeriod, timeout, func(p *ExecutionPlan) {
+		p.Signals = append([]os.Signal(nil), sigs...)
+		p.ReloadSignals = nil
+	})
+}
+
+// IsTerminating reports if the plan is terminating, see MarkTerminating.
 func (p *ExecutionPlan) IsTerminating() bool {
+	p.isTerminatingMutex.RLock()
+	defer p.isTerminatingMutex.RUnlock()
 	return p.isTerminating
 }
 
-// HandlerFunc is used on the HTTP Server Side to support a RESTful way of ready state.
-// See https://kubernetes.io/docs/reference/using-api/health-checks/ for more information
-func (p *ExecutionPlan) HandlerFunc(w http.ResponseWriter, r *http.Request) {
-	if p.IsTerminating() {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		_, _ = w.Write([]byte("terminating"))
-	} else {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
+// MarkTerminating will mark the plan as terminating, without running the shutdown,
+//  so HandlerFunc reports 503 after the ReadinessFailDelay. It's for tests of the
+//  readiness wiring and for manual control, e.g. to take an instance out of rotation.
+// The exit chans are not closed, that takes a signal or Shutdown. It can't be undone.
+func (p *ExecutionPlan) MarkTerminating() {
+	p.markTerminating()
+}
+
+// markTerminating will mark the plan as terminating, keeping the time it first was.
+func (p *ExecutionPlan) markTerminating() {
+	p.isTerminatingMutex.Lock()
+	defer p.isTerminatingMutex.Unlock()
+
+	if !p.isTerminating {
+		p.terminatingSince = p.clock().Now()
 	}
+	p.isTerminating = true
+}
+
+// terminatingFor returns how long the plan has been terminating, zero when it isn't.
+func (p *ExecutionPlan) terminatingFor() time.Duration {
+	p.isTerminatingMutex.RLock()
+	defer p.isTerminatingMutex.RUnlock()
+
+	if !p.isTerminating {
+		return 0
+	}
+	return p.clock().Now().Sub(p.terminatingSince)
 }
 
 // NewExitChan will return a new chan listener to allow for
@@ -82,106 +326,750 @@ func (p *ExecutionPlan) NewExitChan() chan struct{} {
 	return c
 }
 
-func (p *ExecutionPlan) Add(name string, handler ExitOperation) {
+// NewDoneChan will return a new chan listener that's closed once the shutdown has
+//  fully completed, including the final callback. Unlike NewExitChan, which is closed
+//  as soon as the plan is terminating, it's for observers that must act only after
+//  all the cleanup is done. See Done for a shared chan.
+func (p *ExecutionPlan) NewDoneChan() chan struct{} {
+	c := make(chan struct{})
+
+	p.termLock.Lock()
+	defer p.termLock.Unlock()
+
+	select {
+	case <-p.done:
+		close(c)
+	default:
+		p.doneListeners = append(p.doneListeners, c)
+	}
+
+	return c
+}
+
+// NewExitChanCtx is like NewExitChan, but the chan stops being a listener once ctx is
+//  done so short-lived goroutines don't pile up listeners. The chan is never closed
+//  after ctx is done.
+func (p *ExecutionPlan) NewExitChanCtx(ctx context.Context) <-chan struct{} {
+	c := p.NewExitChan()
+
+	go func() {
+		select {
+		case <-c:
+		case <-ctx.Done():
+			p.removeExitChan(c)
+		}
+	}()
+
+	return c
+}
+
+// removeExitChan will remove c from the termListeners,
+//  the slice is copied as it may be read while closing them.
+func (p *ExecutionPlan) removeExitChan(c chan struct{}) {
+	p.termLock.Lock()
+	defer p.termLock.Unlock()
+
+	listeners := make([]chan struct{}, 0, len(p.termListeners))
+	for _, l := range p.termListeners {
+		if l != c {
+			listeners = append(listeners, l)
+		}
+	}
+	p.termListeners = listeners
+}
+
+// Add will register the handler under name to run concurrently with the other callbacks.
+// An error is returned when MaxCallbacks would be exceeded.
+func (p *ExecutionPlan) Add(name string, handler ExitOperation) error {
+	return p.AddWithOptions(name, handler)
+}
+
+// AddMustRun will register the handler under name like AddWithOptions, and when the
+//  Timeout elapses run it right before the forced exit, within MustRunBudget.
+// It's for what must happen regardless, like writing a shutdown marker. Unless it's
+//  marked WithIdempotent it only runs there when it hasn't already been started.
+func (p *ExecutionPlan) AddMustRun(name string, handler ExitOperation, opts ...CallbackOption) error {
+	return p.AddWithOptions(name, handler, append(opts, func(c *callback) {
+		c.mustRun = true
+	})...)
+}
+
+// AddForSignalOnly will register the handler under name like Add, only running it
+//  when the shutdown was triggered by a signal, e.g. to notify of an operator shutdown.
+func (p *ExecutionPlan) AddForSignalOnly(name string, handler ExitOperation) error {
+	return p.register(&callback{
+		name:   name,
+		op:     handler,
+		source: signalSource,
+	})
+}
+
+// AddIf will register the handler under name like Add, calling cond when the shutdown
+//  reaches it to decide if it runs, e.g. to close a cache only if it was enabled.
+// It's skipped when cond returns false, reported in Result.Skipped but not as failed.
+func (p *ExecutionPlan) AddIf(name string, handler ExitOperation, cond func() bool) error {
+	return p.register(&callback{
+		name: name,
+		op:   handler,
+		cond: cond,
+	})
+}
+
+// AddForProgrammaticOnly will register the handler under name like Add, only running it
+//  when the shutdown wasn't triggered by a signal, like a call to Shutdown.
+func (p *ExecutionPlan) AddForProgrammaticOnly(name string, handler ExitOperation) error {
+	return p.register(&callback{
+		name:   name,
+		op:     handler,
+		source: programmaticSource,
+	})
+}
+
+// AddMany will register each of the handlers under its name, see Add.
+// It stops at the first error. The handlers are registered in the random order of
+//  the map, see AddAll to keep the order for the logs and ReverseOrder.
+func (p *ExecutionPlan) AddMany(handlers map[string]ExitOperation) error {
+	for name, handler := range handlers {
+		if err := p.Add(name, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NamedOperation is an ExitOperation along with its name, see AddAll.
+type NamedOperation struct {
+	Name string
+	Op   ExitOperation
+}
+
+// AddAll will register each of the operations like Add, in the order of the slice.
+// It stops at the first error.
+func (p *ExecutionPlan) AddAll(operations []NamedOperation) error {
+	for _, op := range operations {
+		if err := p.Add(op.Name, op.Op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddWithOptions will register the handler under name, just like Add,
+//  with the given options applied to it.
+func (p *ExecutionPlan) AddWithOptions(name string, handler ExitOperation, opts ...CallbackOption) error {
+	c := &callback{
+		name: name,
+		op:   handler,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return p.register(c)
+}
+
+// AddWithRetry will register the handler under name like Add, retrying it when it
+//  fails, see WithRetry.
+func (p *ExecutionPlan) AddWithRetry(name string, handler ExitOperation, retries int, backoff time.Duration) error {
+	return p.AddWithOptions(name, handler, WithRetry(retries, backoff))
+}
+
+// AddWithMeta will register the handler under name like Add, tagged with meta,
+//  see WithMeta.
+func (p *ExecutionPlan) AddWithMeta(name string, handler ExitOperation, meta map[string]string) error {
+	return p.AddWithOptions(name, handler, WithMeta(meta))
+}
+
+// Sequential will register the handler to run one-by-one with the other sequential
+//  callbacks, in the order they were registered. See SequentialFirst for when these
+//  run compared to the concurrent callbacks.
+func (p *ExecutionPlan) Sequential(name string, handler ExitOperation) error {
+	return p.register(&callback{
+		name:       name,
+		op:         handler,
+		sequential: true,
+	})
+}
+
+func (p *ExecutionPlan) register(c *callback) error {
 	p.callbacksMutex.Lock()
 	defer p.callbacksMutex.Unlock()
-	p.callbacks[name] = handler
+	return p.registerLocked(c)
+}
+
+// registerLocked is register with the callbacksMutex already held.
+func (p *ExecutionPlan) registerLocked(c *callback) error {
+	if _, exists := p.callbacks[c.name]; !exists && p.MaxCallbacks > 0 && len(p.callbacks) >= p.MaxCallbacks {
+		return fmt.Errorf("%w: %q would exceed %d", ErrTooManyCallbacks, c.name, p.MaxCallbacks)
+	}
+
+	c.index = p.callbackIndex
+	p.callbackIndex++
+	p.callbacks[c.name] = c
+	return nil
+}
+
+// SetSignals will replace the signals that trigger the shutdown.
+// If the plan is already started the running listener is re-armed with the new set.
+func (p *ExecutionPlan) SetSignals(sigs ...os.Signal) {
+	p.signalsMutex.Lock()
+	p.Signals = sigs
+	p.signalsMutex.Unlock()
+
+	select {
+	case p.signalsChanged <- struct{}{}:
+	default:
+	}
+}
+
+func (p *ExecutionPlan) signals() []os.Signal {
+	p.signalsMutex.RLock()
+	defer p.signalsMutex.RUnlock()
+	return p.Signals
+}
+
+// watchedSignals returns the Signals along with the ReloadSignals.
+func (p *ExecutionPlan) watchedSignals() []os.Signal {
+	sigs := append([]os.Signal(nil), p.signals()...)
+	for _, sig := range p.ReloadSignals {
+		if !containsSignal(sigs, sig) {
+			sigs = append(sigs, sig)
+		}
+	}
+	return sigs
+}
+
+// listen will watch the signals until the shutdown is done, re-arming the
+//  listener each time SetSignals is called. Each signal is handled with the
+//  FirstSignalAction or SecondSignalAction depending on how many were received.
+// The cancellation of ctx is a trigger source as well.
+func (p *ExecutionPlan) listen(ctx context.Context) {
+	s := make(chan os.Signal, 1)
+
+	// Set syscalls to listen for using the chan
+	sigs := p.watchedSignals()
+	notify(s, sigs)
+	p.debugf("watching the signals %v", sigs)
+	defer func() {
+		signal.Stop(s)
+	}()
+
+	received := 0
+	canceled := ctx.Done()
+	for {
+		select {
+		case <-canceled:
+			// Only the cancellation triggers, a deadline is the budget of the shutdown.
+			canceled = nil
+			if errors.Is(ctx.Err(), context.Canceled) {
+				p.logf("context canceled, shutting down")
+				go p.fire(context.Background(), nil)
+			}
+		case sig := <-s:
+			if !containsSignal(p.signals(), sig) {
+				p.observeSignal(sig)
+				p.reload(sig)
+				continue
+			}
+			received++
+			if !p.handleSignal(ctx, sig, received, s) {
+				received--
+			}
+		case <-p.done:
+			return
+		case <-p.signalsChanged:
+			sigs := p.watchedSignals()
+
+			// Listen on the new set before releasing the old one so no signal is missed,
+			//  one that was already delivered is kept if it's still part of the set.
+			next := make(chan os.Signal, 1)
+			notify(next, sigs)
+			signal.Stop(s)
+
+			select {
+			case sig := <-s:
+				if containsSignal(sigs, sig) {
+					select {
+					case next <- sig:
+					default:
+					}
+				}
+			default:
+			}
+			s = next
+		}
+	}
+}
+
+// notify is signal.Notify, except no signal is relayed when sigs is empty
+//  (instead of all of them).
+func notify(c chan<- os.Signal, sigs []os.Signal) {
+	if len(sigs) > 0 {
+		signal.Notify(c, sigs...)
+	}
+}
+
+// handleSignal will apply the SignalAction for the nth signal received.
+// It returns false when the signal was not confirmed and should not be counted.
+func (p *ExecutionPlan) handleSignal(ctx context.Context, sig os.Signal, n int, s <-chan os.Signal) bool {
+	action := p.FirstSignalAction
+	if n > 1 {
+		action = p.SecondSignalAction
+	}
+
+	switch action {
+	case Ignore:
+		p.warnf("ignoring %s", sig)
+		p.observeSignal(sig)
+	case ForceExit:
+		p.errorf("%s received, force exit", sig)
+		p.setState(ForcedExit)
+		p.forceExit()
+	case Drain:
+		select {
+		case <-p.shutdownRequested:
+			// Already shutting down.
+			return true
+		default:
+		}
+		if !p.confirm(sig, s) {
+			return false
+		}
+		p.fire(ctx, sig)
+	}
+	return true
+}
+
+func containsSignal(sigs []os.Signal, sig os.Signal) bool {
+	for _, s := range sigs {
+		if s == sig {
+			return true
+		}
+	}
+	return false
+}
+
+// Done returns a chan that's closed once the shutdown has completed.
+func (p *ExecutionPlan) Done() <-chan struct{} {
+	return p.done
+}
+
+// Err returns the aggregated error of the shutdown, a *ShutdownError when
+//  any exit operation has failed. It's nil until Done is closed, and when
+//  the SuccessThreshold is met (Result still carries the errors).
+func (p *ExecutionPlan) Err() error {
+	if p.CompletedCleanly() {
+		return nil
+	}
+	return p.Result().Err()
+}
+
+// CompletedCleanly reports if the shutdown has completed and enough exit operations
+//  have succeeded to meet the SuccessThreshold.
+func (p *ExecutionPlan) CompletedCleanly() bool {
+	select {
+	case <-p.done:
+	default:
+		return false
+	}
+	return p.Result().meets(p.SuccessThreshold)
 }
 
 func (p *ExecutionPlan) Finally(handler ExitOperation) {
+	p.FinallyWithResult(func(ctx context.Context, _ error) error {
+		return handler(ctx)
+	})
+}
+
+// FinallyWithResult is like Finally, but handler is given the aggregated error of the
+//  callbacks that have run before it (see Err), nil when they all have succeeded.
+func (p *ExecutionPlan) FinallyWithResult(handler func(ctx context.Context, err error) error) {
 	p.finalCallback = handler
+	p.finalRetries, p.finalBackoff = 0, 0
+}
+
+// FinallyWithRetry is like Finally, but handler is called again up to retries times
+//  when it fails, see WithRetry. The retries are bound by what's left of the Timeout
+//  once the callbacks have completed.
+func (p *ExecutionPlan) FinallyWithRetry(handler ExitOperation, retries int, backoff time.Duration) {
+	p.Finally(handler)
+	p.finalRetries, p.finalBackoff = retries, backoff
 }
 
-// Wait will wait until the program gets an exit signal and all handlers have succeeded.
-// If used on the main thread, this will allow it to die
-func (p *ExecutionPlan) Wait(ctx context.Context) {
+// Wait will wait until the program gets an exit signal and all handlers have completed,
+//  returning the aggregated error of the shutdown (see Err).
+// If used on the main thread, this will allow it to die, e.g. with a non-zero
+//  exit code when the error isn't nil.
+// If ctx carries a deadline the GradePeriod and Timeout are capped to fit within it,
+//  see Start for details.
+func (p *ExecutionPlan) Wait(ctx context.Context) error {
 	<-p.Start(ctx)
+	return p.Err()
 }
 
 // Start will begin watching the os.Signal for the set interrupts.
 // If a signal is set then everything kicks into action.
+//
+// The deadline of ctx (if any) is treated as the overall shutdown budget, like the
+//  terminationGracePeriodSeconds given by Kubernetes. When the remaining time is
+//  shorter than GradePeriod + Timeout, the grade period is shrunk first and then the
+//  callback timeout, to avoid the process being killed before it exits by itself.
+// Canceling ctx triggers the shutdown like Shutdown, with the full GradePeriod and
+//  Timeout. Reaching its deadline doesn't.
 func (p *ExecutionPlan) Start(ctx context.Context) chan struct{} {
 
 	// Used to prevent two calls to wait, having two listeners
 	p.interruptListen.Lock()
 	defer p.interruptListen.Unlock()
+	atomic.StoreInt32(&p.started, 1)
 
 	// Chan to be used to allow execution to continue
 	sigChannel := make(chan struct{})
 
 	// Create a new goroutines to kick off the exit method calls once the os.Signal hits.
 	go func() {
-		s := make(chan os.Signal, 1)
+		// Close the signal channel for the holding callback, even if listening panics.
+		defer close(sigChannel)
+		defer p.recoverPanic(nil)
 
-		// Set syscalls to listen for using the chan
-		signal.Notify(s, p.Signals...)
+		// Wait for an interrupt to be triggered, or a call to Shutdown.
+		p.listen(ctx)
+	}()
 
-		// Wait for an interrupt to be triggered.
-		<-s
+	return sigChannel
+}
 
-		// Indicate internally the app is going to shutdown and to not accept
-		//  any new connections.
-		log.Println("interrupt received...")
-		p.isTerminatingMutex.Lock()
-		p.isTerminating = true
-		p.isTerminatingMutex.Unlock()
+// Shutdown will run the shutdown sequence now, without waiting for a signal, and
+//  return the aggregated error once it has completed. The sequence only runs once,
+//  if it's already running (from a signal or another call) this waits for it to complete.
+// Canceling ctx cuts the shutdown short: the drain is interrupted, the running
+//  callbacks are abandoned with ErrCanceled and the others are skipped, and the
+//  final callback is run with the canceled context before the Result is stored.
+func (p *ExecutionPlan) Shutdown(ctx context.Context) error {
+	p.fire(ctx, nil)
+	<-p.done
+	return p.Err()
+}
 
-		// Close the termListener chan(s) to send a signal that it's received a terminating signal
-		go func(termListeners []chan struct{}) {
-			for _, c := range termListeners {
-				close(c)
-			}
-		}(p.termListeners)
+// recoverPanic will recover a panic of the plan itself, logging it and recording it
+//  as the "panic" error of the shutdown, which is then done so Wait doesn't hang.
+func (p *ExecutionPlan) recoverPanic(sig os.Signal) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	p.errorf("panic: %v", r)
+	p.outcome.record("panic", fmt.Errorf("exitplan: panic: %v", r), 0)
+	p.finish(sig)
+}
 
-		// Wait to allow for connections to drain.
-		time.Sleep(p.GradePeriod)
+// run is the shutdown sequence, from marking the plan terminating to the final callback.
+func (p *ExecutionPlan) run(ctx context.Context, sig os.Signal) {
+	ctx = p.correlate(ctx)
+	ctx = context.WithValue(ctx, disposeKey{}, p)
+	ctx = p.withSignal(ctx, sig)
+	p.traceBegin(sig)
+	go p.watchGoroutines()
 
-		// Set timeout for the operations to complete and prevent system hang and prevent SIGKILL
-		log.Println("shutting down")
-		timeoutFunc := time.AfterFunc(p.Timeout, func() {
-			log.Printf("timeout %d ms has elapsed, force exit", p.Timeout.Milliseconds())
-			os.Exit(0)
+	// Indicate internally the app is going to shutdown and to not accept
+	//  any new connections.
+	if sig != nil {
+		p.logf("interrupt received...")
+	} else {
+		p.logf("shutdown requested...")
+	}
+	p.logPlanned(sig)
+	p.markTerminating()
+	p.setState(Draining)
+
+	// Close the termListener chan(s) to send a signal that it's received a terminating signal,
+	//  before draining begins so every listener is notified at the start of it.
+	p.termLock.Lock()
+	termListeners := p.termListeners
+	p.termLock.Unlock()
+	for _, c := range termListeners {
+		close(c)
+	}
+
+	// Nothing to drain or dispose, skip the timers and go straight to done.
+	if p.isEmpty() {
+		p.finish(sig)
+		return
+	}
+
+	// Release the leases first, then run the preStop callbacks if the
+	//  PreStopHandler was not called.
+	p.releaseLeases(ctx)
+	_ = p.runPreStop(ctx)
+
+	// Fit the internal timers within the external budget of the context.
+	gradePeriod, timeout := p.budget(ctx, sig)
+	if sig != nil && containsSignal(p.SkipDrainSignals, sig) {
+		p.logf("skipping drain for %s", sig)
+		gradePeriod = 0
+	}
+
+	// Wait to allow for connections to drain.
+	drainStart := p.clock().Now()
+	p.drain(ctx, gradePeriod)
+	p.recordDrain(gradePeriod, p.clock().Now().Sub(drainStart))
+	p.readinessDrained()
+
+	// Set timeout for the operations to complete and prevent system hang and prevent SIGKILL
+	p.logf("shutting down")
+	p.setState(Disposing)
+	deadline := callbackDeadline(ctx, p.clock().Now(), timeout)
+	p.deadlineMutex.Lock()
+	p.deadline = deadline
+	p.deadlineMutex.Unlock()
+	var timeoutFunc Timer = noopTimer{}
+	if !p.DisableForceExit {
+		timeoutFunc = p.clock().AfterFunc(deadline.Sub(p.clock().Now()), func() {
+			p.errorf("timeout %d ms has elapsed, force exit", timeout.Milliseconds())
+			p.logForcedSummary(sig)
+			p.setState(ForcedExit)
+			p.runMustRun()
+			p.forceExit()
 		})
+	}
 
-		var wg sync.WaitGroup
+	concurrent, sequential := p.splitCallbacks()
+	concurrent, sequential = runningFor(concurrent, sig), runningFor(sequential, sig)
+	results := p.outcome
+	run := p.newShutdown(ctx, deadline)
+	if mode, ok := p.SignalMode[sig]; ok {
+		run.callbackTimeout = mode.CallbackTimeout
+	}
 
-		// Execute exit operations async to allow for a faster shutdown process.
-		p.callbacksMutex.RLock()
-		for key, op := range p.callbacks {
-			wg.Add(1)
-			go func(innerKey string, dispose ExitOperation) {
-				defer wg.Done()
+	// Execute the exit operations and wait for them to complete.
+	// If the timeoutFunc expires, kill the entire process.
+	if p.SequentialFirst {
+		run.runSequential(sequential)
+	}
+	run.runConcurrent(concurrent)
+	if !p.SequentialFirst {
+		run.runSequential(sequential)
+	}
+	p.runOnDispose(run)
 
-				log.Printf("disposing: %s", innerKey)
-				if err := dispose(ctx); err != nil {
-					log.Printf("%s: dispose failed: %s", innerKey, err.Error())
-					return
-				}
-				log.Printf("%s was disposed gracefully", innerKey)
-			}(key, op)
+	// Stop the timeout function for os.Exit to allow the final callbacks to run.
+	timeoutFunc.Stop()
+	if p.DisableForceExit && !p.clock().Now().Before(deadline) {
+		p.errorf("timeout %d ms has elapsed", timeout.Milliseconds())
+		results.timeout()
+	}
+
+	// Final cleanup callback
+	// Successfully cleaned up connections and exit operations
+	p.setState(Finalizing)
+	if p.finalCallback != nil {
+		start := p.clock().Now()
+		prior := results.result(sig).Err()
+		final := &callback{
+			name: "final",
+			op: func(ctx context.Context) error {
+				return p.finalCallback(ctx, prior)
+			},
+			retries: p.finalRetries,
+			backoff: p.finalBackoff,
+		}
+		err := run.invoke(run.ctx, final, run.logger)
+		if err != nil {
+			p.errorf("final: dispose failed: %s", err.Error())
+		} else {
+			p.debugf("final was disposed gracefully")
 		}
-		p.callbacksMutex.RUnlock()
+		results.record("final", err, p.clock().Now().Sub(start))
+	}
 
-		// Wait for all Exit Operations to complete their exit operation.
-		// If the timeoutFunc expires, kill the entire process.
-		wg.Wait()
+	p.finish(sig)
+}
 
-		// Stop the timeout function for os.Exit to allow the final callbacks to run.
-		timeoutFunc.Stop()
+// newShutdown returns the state to run callbacks until deadline.
+func (p *ExecutionPlan) newShutdown(ctx context.Context, deadline time.Time) *shutdown {
+	return &shutdown{
+		ctx:            ctx,
+		deadline:       deadline,
+		retryDelay:     p.RetryNotReadyDelay,
+		maxConcurrency: p.MaxConcurrency,
+		results:        p.outcome,
+		logger:         loggerFunc(p.print),
+		level:          p.LogLevel,
+		groupLogs:      p.GroupLogs,
+		clock:          p.clock(),
+		executor:       p.executor(),
+		ran:            &p.ran,
+	}
+}
 
-		// Final cleanup callback
-		// Successfully cleaned up connections and exit operations
-		if p.finalCallback != nil {
-			if err := p.finalCallback(ctx); err != nil {
-				log.Printf("final: dispose failed: %s", err.Error())
-				return
-			}
-			log.Println("final was disposed gracefully")
+// finish will store the Result of the shutdown and mark the plan Done, once.
+func (p *ExecutionPlan) finish(sig os.Signal) {
+	p.finishOnce.Do(func() {
+		p.resultMutex.Lock()
+		p.result = p.outcome.result(sig)
+		p.resultMutex.Unlock()
+
+		p.closeProgress()
+		p.logSummary(p.result)
+		p.recordOutcome(p.result.meets(p.SuccessThreshold))
+		p.setState(Done)
+
+		p.termLock.Lock()
+		close(p.done)
+		for _, c := range p.doneListeners {
+			close(c)
 		}
+		p.doneListeners = nil
+		p.termLock.Unlock()
+	})
+}
 
-		// Close the signal channel for the holding callback.
-		close(sigChannel)
-	}()
+// isEmpty reports if the plan has no grade period and no callbacks to run.
+func (p *ExecutionPlan) isEmpty() bool {
+	gradePeriod, _ := p.timeouts()
 
-	return sigChannel
+	p.callbacksMutex.RLock()
+	defer p.callbacksMutex.RUnlock()
+	return len(p.callbacks) == 0 && gradePeriod == 0 && p.finalCallback == nil
+}
+
+// drain will wait for the grade period to elapse, returning early
+//  when ctx is done to accelerate the shutdown. With AwaitProbe the grade
+//  period only begins once HandlerFunc has reported terminating. It ends
+//  early once the gauges of DrainUntilZero are all at zero.
+func (p *ExecutionPlan) drain(ctx context.Context, gradePeriod time.Duration) {
+	if gradePeriod <= 0 {
+		return
+	}
+
+	if p.AwaitProbe > 0 {
+		select {
+		case <-p.probed:
+		case <-p.clock().After(p.AwaitProbe):
+			p.warnf("readiness not probed within %d ms, draining", p.AwaitProbe.Milliseconds())
+		case <-ctx.Done():
+			p.warnf("drain interrupted, context is done")
+			return
+		}
+	}
+
+	elapsed := p.clock().After(gradePeriod)
+	if len(p.drainGauges) == 0 {
+		select {
+		case <-elapsed:
+		case <-ctx.Done():
+			p.warnf("drain interrupted, context is done")
+		}
+		return
+	}
+
+	for !p.drainedToZero() {
+		select {
+		case <-elapsed:
+			return
+		case <-ctx.Done():
+			p.warnf("drain interrupted, context is done")
+			return
+		case <-p.clock().After(DrainPollInterval):
+		}
+	}
+	p.logf("active work has reached zero, ending the drain early")
+}
+
+// DrainPollInterval is the delay between the polls of the gauges of DrainUntilZero.
+var DrainPollInterval = 100 * time.Millisecond
+
+// DrainUntilZero will end the GradePeriod early once gauge reports zero, along with
+//  the other gauges given to it, e.g. the open transactions or active streams.
+// The gauges are polled every DrainPollInterval. It must be called before Start.
+func (p *ExecutionPlan) DrainUntilZero(gauge func() int) {
+	p.drainGauges = append(p.drainGauges, gauge)
+}
+
+// drainedToZero reports if all the gauges of DrainUntilZero are at zero.
+func (p *ExecutionPlan) drainedToZero() bool {
+	for _, gauge := range p.drainGauges {
+		if gauge() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// callbacksDeadline returns the deadline of the running callbacks,
+//  the zero time.Time until they have started.
+func (p *ExecutionPlan) callbacksDeadline() time.Time {
+	p.deadlineMutex.RLock()
+	defer p.deadlineMutex.RUnlock()
+	return p.deadline
+}
+
+// callbackDeadline returns the deadline of the callbacks beginning at start,
+//  capped to the deadline of ctx so the time spent draining is accounted for.
+func callbackDeadline(ctx context.Context, start time.Time, timeout time.Duration) time.Time {
+	deadline := start.Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		return d
+	}
+	return deadline
+}
+
+// runMustRun will run the callbacks registered with AddMustRun concurrently, within MustRunBudget.
+// They're run in their own goroutines rather than on the Executor, it's likely
+//  saturated by the hung callbacks that caused the forced exit.
+func (p *ExecutionPlan) runMustRun() {
+	callbacks := p.mustRunCallbacks()
+	if len(callbacks) == 0 {
+		return
+	}
+
+	p.debugf("running must run callbacks")
+	s := p.newShutdown(context.Background(), p.clock().Now().Add(MustRunBudget))
+	s.executor = goExecutor{}
+	s.runConcurrent(callbacks)
+}
+
+// forceExit will exit the process, escalating to SIGKILL if KillOnTimeout is set.
+// The exit status is 1, the cleanup not having completed.
+func (p *ExecutionPlan) forceExit() {
+	p.recordOutcome(false)
+	if p.KillOnTimeout {
+		escalateKill(time.Second)
+	}
+	os.Exit(1)
+}
+
+// budget returns the grade period (with its jitter) and timeout to use for a shutdown
+//  triggered by sig (see SignalMode), capped to the time remaining before the
+//  deadline of ctx. The grade period is shrunk first, then the timeout.
+func (p *ExecutionPlan) budget(ctx context.Context, sig os.Signal) (gradePeriod, timeout time.Duration) {
+	gradePeriod, timeout = p.timeouts()
+	gradePeriod += jitter(p.GradePeriodJitter)
+	if mode, ok := p.SignalMode[sig]; ok {
+		if mode.GradePeriod != nil {
+			gradePeriod = *mode.GradePeriod
+		}
+		if mode.Timeout != nil {
+			timeout = *mode.Timeout
+		}
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return gradePeriod, timeout
+	}
+
+	remaining := deadline.Sub(p.clock().Now())
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	if gradePeriod+timeout <= remaining {
+		return gradePeriod, timeout
+	}
+	if timeout <= remaining {
+		return remaining - timeout, timeout
+	}
+	return 0, remaining
 }
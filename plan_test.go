@@ -0,0 +1,167 @@
+package exitplan
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestBudgetFitsWithinContextDeadline(t *testing.T) {
+	tests := []struct {
+		name        string
+		remaining   time.Duration
+		gradePeriod time.Duration
+		timeout     time.Duration
+	}{
+		{"fits", time.Minute, 30 * time.Second, 10 * time.Second},
+		{"grade period shrunk", 15 * time.Second, 5 * time.Second, 10 * time.Second},
+		{"timeout shrunk", 4 * time.Second, 0, 4 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, clock := newTestPlan(30*time.Second, 10*time.Second)
+
+			ctx, cancel := withClockDeadline(context.Background(), clock, clock.Now().Add(tt.remaining))
+			defer cancel()
+
+			gradePeriod, timeout := p.budget(ctx, nil)
+			if gradePeriod != tt.gradePeriod || timeout != tt.timeout {
+				t.Errorf("budget() = %s, %s, want %s, %s", gradePeriod, timeout, tt.gradePeriod, tt.timeout)
+			}
+		})
+	}
+}
+
+func TestContextDeadlineShrinksCallbackBudget(t *testing.T) {
+	p, clock := newTestPlan(30*time.Second, 10*time.Second)
+
+	// The deadline is on the FakeClock, like the one of the plan.
+	start := clock.Now()
+	ctx, cancel := withClockDeadline(context.Background(), clock, start.Add(15*time.Second))
+	defer cancel()
+
+	var deadline time.Time
+	_ = p.Add("db", func(ctx context.Context) error {
+		deadline = p.callbacksDeadline()
+		return nil
+	})
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p.Shutdown(ctx)
+	}()
+
+	// The GradePeriod is shrunk to 5s so the Timeout still fits.
+	awaitTimers(t, clock, 1)
+	clock.Advance(5 * time.Second)
+
+	if err := <-errc; err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+	if want := start.Add(15 * time.Second); !deadline.Equal(want) {
+		t.Errorf("callbacks deadline = %v, want %v", deadline, want)
+	}
+}
+
+func TestCanceledContextShortensDrain(t *testing.T) {
+	p, clock := newTestPlan(30*time.Second, 10*time.Second)
+	start := clock.Now()
+	_ = p.Add("db", func(ctx context.Context) error { return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p.Shutdown(ctx)
+	}()
+
+	// Draining, the clock is never advanced through the GradePeriod.
+	awaitTimers(t, clock, 1)
+	cancel()
+
+	select {
+	case <-errc:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the drain was not interrupted by the canceled context")
+	}
+	if !clock.Now().Equal(start) {
+		t.Errorf("clock moved to %v", clock.Now())
+	}
+}
+
+func BenchmarkShutdown(b *testing.B) {
+	newPlan := func() *ExecutionPlan {
+		p := NewPlanWithSignals(0, time.Second)
+		p.Logger = log.New(ioutil.Discard, "", 0)
+		return p
+	}
+
+	b.Run("empty", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = newPlan().Shutdown(context.Background())
+		}
+	})
+	b.Run("full", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p := newPlan()
+			_ = p.Add("noop", func(ctx context.Context) error { return nil })
+			_ = p.Shutdown(context.Background())
+		}
+	})
+}
+
+func TestPanicIsRecordedAndWaitReturns(t *testing.T) {
+	p, _ := newTestPlan(0, time.Second)
+	p.Finally(func(ctx context.Context) error {
+		panic("boom")
+	})
+
+	go func() {
+		_ = p.Shutdown(context.Background())
+	}()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p.Wait(context.Background())
+	}()
+
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Fatal("Wait() = nil, want the panic error")
+		}
+		if _, ok := p.Result().Errors["panic"]; !ok {
+			t.Errorf("Result().Errors = %v, want a panic error", p.Result().Errors)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait has not returned after the panic")
+	}
+}
+
+func TestNewPlanWithSignalsIgnoresHUPReloadByDefault(t *testing.T) {
+	HUPReloadByDefault = true
+	defer func() {
+		HUPReloadByDefault = false
+	}()
+
+	if sigs := NewPlanWithSignals(0, time.Second).WatchedSignals(); len(sigs) != 0 {
+		t.Errorf("WatchedSignals() = %v, want none", sigs)
+	}
+}
+
+func TestCanceledStartContextTriggersShutdown(t *testing.T) {
+	p, _ := newTestPlan(0, time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := p.Start(ctx)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the canceled context has not triggered the shutdown")
+	}
+	if p.State() != Done {
+		t.Errorf("State() = %s, want %s", p.State(), Done)
+	}
+}
@@ -0,0 +1,25 @@
+// Package pool provides a bounded Executor for exitplan, to limit the goroutines
+//  running the callbacks of large plans.
+package pool
+
+// Pool runs the submitted funcs on at most n goroutines at once.
+type Pool struct {
+	slots chan struct{}
+}
+
+// New returns a Pool running at most n funcs at once, n below 1 is treated as 1.
+func New(n int) *Pool {
+	if n < 1 {
+		n = 1
+	}
+	return &Pool{slots: make(chan struct{}, n)}
+}
+
+// Submit will run f in its own goroutine, blocking until one of the n slots is free.
+func (p *Pool) Submit(f func()) {
+	p.slots <- struct{}{}
+	go func() {
+		defer func() { <-p.slots }()
+		f()
+	}()
+}
@@ -0,0 +1,81 @@
+package exitplan
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// LeaseReleaseTimeout bounds the callbacks registered with AddLeaseRelease.
+var LeaseReleaseTimeout = 2 * time.Second
+
+// AddPreStop will register the handler under name to run when Kubernetes calls the
+//  preStop hook, see PreStopHandler. This is before SIGTERM is sent to the container.
+// When the hook isn't called they run when the shutdown begins, before draining.
+func (p *ExecutionPlan) AddPreStop(name string, handler ExitOperation) error {
+	return p.register(&callback{
+		name:    name,
+		op:      handler,
+		preStop: true,
+	})
+}
+
+// PreStopHandler is used as the preStop httpGet hook of a Kubernetes container.
+// It marks the plan as terminating, so HandlerFunc reports it, and runs the callbacks
+//  registered with AddPreStop concurrently, within the Timeout. It responds once they
+//  have completed, as Kubernetes waits for the hook before sending SIGTERM.
+// See https://kubernetes.io/docs/concepts/containers/container-lifecycle-hooks/
+func (p *ExecutionPlan) PreStopHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if err := p.runPreStop(context.Background()); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+// AddLeaseRelease will register release under name to run first when the shutdown
+//  begins, before the preStop callbacks and draining, so a leadership lease (etcd,
+//  consul, ...) is released and a new leader elected while this one still serves.
+// It's bound by LeaseReleaseTimeout as releasing a lease should be quick.
+func (p *ExecutionPlan) AddLeaseRelease(name string, release ExitOperation) error {
+	return p.register(&callback{
+		name:  name,
+		op:    release,
+		lease: true,
+	})
+}
+
+// releaseLeases will run the callbacks registered with AddLeaseRelease concurrently.
+func (p *ExecutionPlan) releaseLeases(ctx context.Context) {
+	callbacks := p.leaseCallbacks()
+	if len(callbacks) == 0 {
+		return
+	}
+
+	p.debugf("releasing leases")
+	p.newShutdown(ctx, p.clock().Now().Add(LeaseReleaseTimeout)).runConcurrent(callbacks)
+}
+
+// runPreStop will run the preStop callbacks once, returning the aggregated error.
+func (p *ExecutionPlan) runPreStop(ctx context.Context) error {
+	p.preStopOnce.Do(func() {
+		p.markTerminating()
+
+		callbacks := p.preStopCallbacks()
+		if len(callbacks) == 0 {
+			return
+		}
+
+		_, timeout := p.timeouts()
+		p.debugf("running preStop callbacks")
+		p.newShutdown(ctx, p.clock().Now().Add(timeout)).runConcurrent(callbacks)
+	})
+
+	r := p.outcome.result(nil)
+	return r.Err()
+}
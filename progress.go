@@ -0,0 +1,65 @@
+package exitplan
+
+import (
+	"time"
+)
+
+// CallbackResult is the outcome of a single callback, see Progress.
+type CallbackResult struct {
+	// Name is the name of the callback, "final" for the final callback.
+	Name string
+	// Err is the error of the callback, nil when it has succeeded.
+	Err error
+	// Duration is how long the callback has run for.
+	Duration time.Duration
+}
+
+// Progress will return a new chan receiving the CallbackResult of each callback as
+//  it completes, to render the progress of the shutdown live.
+// The chan is buffered and results are dropped for a slow reader, so the shutdown
+//  is never stalled. It's closed once the plan is Done.
+func (p *ExecutionPlan) Progress() <-chan CallbackResult {
+	c := make(chan CallbackResult, 64)
+
+	p.stateLock.Lock()
+	if p.progressClosed {
+		close(c)
+	} else {
+		p.progressListeners = append(p.progressListeners, c)
+	}
+	p.stateLock.Unlock()
+
+	return c
+}
+
+// recorded is called with the outcome of each callback as it's recorded.
+func (p *ExecutionPlan) recorded(r CallbackResult) {
+	p.traceCallback(r)
+	p.publishProgress(r)
+}
+
+// publishProgress will send r to the Progress listeners, without blocking.
+func (p *ExecutionPlan) publishProgress(r CallbackResult) {
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	for _, c := range p.progressListeners {
+		select {
+		case c <- r:
+		default:
+		}
+	}
+}
+
+// closeProgress will close the Progress listeners, the chans of Progress being closed
+//  from then on.
+func (p *ExecutionPlan) closeProgress() {
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	p.progressClosed = true
+	for _, c := range p.progressListeners {
+		close(c)
+	}
+	p.progressListeners = nil
+}
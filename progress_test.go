@@ -0,0 +1,29 @@
+package exitplan
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProgressFromRecordOutcomeIsClosed(t *testing.T) {
+	p, _ := newTestPlan(0, time.Second)
+
+	var progress <-chan CallbackResult
+	p.RecordOutcome = func(clean bool, d time.Duration) {
+		// Past the close of the listeners, not yet Done.
+		progress = p.Progress()
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+
+	select {
+	case _, ok := <-progress:
+		if ok {
+			t.Error("Progress() received a result after the shutdown")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Progress() is not closed once the plan is Done")
+	}
+}
@@ -0,0 +1,48 @@
+package exitplan
+
+import (
+	"sync/atomic"
+)
+
+// AfterReadinessDrained will call f in its own goroutine once the load balancer has
+//  most likely stopped sending traffic: after HandlerFunc has reported terminating
+//  ReadinessDrainedProbes times, or at the latest once the GradePeriod has ended.
+// It's to begin tearing down what serves the traffic, like connection pools.
+// f is called immediately if that point has already been reached.
+func (p *ExecutionPlan) AfterReadinessDrained(f func()) {
+	p.readinessMutex.Lock()
+	defer p.readinessMutex.Unlock()
+
+	if p.readinessIsDrained {
+		go f()
+		return
+	}
+	p.readinessHooks = append(p.readinessHooks, f)
+}
+
+// probedTerminating will count a probe that was reported terminating by HandlerFunc.
+func (p *ExecutionPlan) probedTerminating() {
+	n := p.ReadinessDrainedProbes
+	if n < 1 {
+		n = 1
+	}
+	if atomic.AddInt32(&p.terminatingProbes, 1) >= int32(n) {
+		p.readinessDrained()
+	}
+}
+
+// readinessDrained will call the hooks of AfterReadinessDrained, once.
+func (p *ExecutionPlan) readinessDrained() {
+	p.readinessMutex.Lock()
+	defer p.readinessMutex.Unlock()
+
+	if p.readinessIsDrained {
+		return
+	}
+	p.readinessIsDrained = true
+
+	for _, f := range p.readinessHooks {
+		go f()
+	}
+	p.readinessHooks = nil
+}
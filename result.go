@@ -0,0 +1,146 @@
+package exitplan
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Result is the summary of a completed shutdown.
+type Result struct {
+	// Clean is true when every exit operation, including the final callback, has succeeded.
+	Clean bool
+	// Errors is keyed by the name of the failed callback, "final" for the final callback.
+	Errors map[string]error
+	// Durations is keyed by the name of the callback, "final" for the final callback.
+	Durations map[string]time.Duration
+	// Signal is the signal that has triggered the shutdown.
+	Signal os.Signal
+	// TimedOut is true when the Timeout has elapsed before the callbacks have completed,
+	//  only reported with DisableForceExit as the process exits otherwise.
+	TimedOut bool
+	// Skipped are the names of the callbacks registered with AddIf that were skipped, sorted.
+	Skipped []string
+}
+
+// Err returns the aggregated error of the Result, nil when it's Clean.
+func (r Result) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return &ShutdownError{Errors: r.Errors}
+}
+
+// meets reports if enough exit operations have succeeded to meet threshold,
+//  see ExecutionPlan.SuccessThreshold.
+func (r Result) meets(threshold float64) bool {
+	if r.Clean {
+		return true
+	}
+
+	total := len(r.Durations)
+	succeeded := float64(total - len(r.Errors))
+	switch {
+	case threshold <= 0:
+		return false
+	case threshold < 1:
+		return succeeded >= threshold*float64(total)
+	}
+	return succeeded >= threshold
+}
+
+// StartResult is like Start, but delivers the Result of the shutdown
+//  over the returned chan once it has completed.
+func (p *ExecutionPlan) StartResult(ctx context.Context) <-chan Result {
+	c := make(chan Result, 1)
+	done := p.Start(ctx)
+
+	go func() {
+		<-done
+		c <- p.Result()
+		close(c)
+	}()
+
+	return c
+}
+
+// Result returns the Result of the shutdown, the zero Result until Done is closed.
+func (p *ExecutionPlan) Result() Result {
+	p.resultMutex.RLock()
+	defer p.resultMutex.RUnlock()
+	return p.result
+}
+
+// outcome collects the errors and durations of the callbacks during a shutdown.
+type outcome struct {
+	mu        sync.Mutex
+	errs      map[string]error
+	durations map[string]time.Duration
+	onRecord  func(r CallbackResult)
+	timedOut  bool
+	skipped   []string
+}
+
+func newOutcome() *outcome {
+	return &outcome{
+		errs:      make(map[string]error),
+		durations: make(map[string]time.Duration),
+	}
+}
+
+func (o *outcome) record(name string, err error, d time.Duration) {
+	o.mu.Lock()
+	o.durations[name] = d
+	if err != nil {
+		o.errs[name] = err
+	} else {
+		// e.g. a must run callback skipped by the normal path, then run by the forced exit.
+		delete(o.errs, name)
+	}
+	o.mu.Unlock()
+
+	if o.onRecord != nil {
+		o.onRecord(CallbackResult{Name: name, Err: err, Duration: d})
+	}
+}
+
+// skip will record the callback name as skipped.
+func (o *outcome) skip(name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.skipped = append(o.skipped, name)
+}
+
+// timeout will mark the shutdown as TimedOut.
+func (o *outcome) timeout() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.timedOut = true
+}
+
+// result returns a copy of what has been recorded as a Result.
+func (o *outcome) result(sig os.Signal) Result {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	r := Result{
+		Clean:     len(o.errs) == 0,
+		Errors:    make(map[string]error, len(o.errs)),
+		Durations: make(map[string]time.Duration, len(o.durations)),
+		Signal:    sig,
+		TimedOut:  o.timedOut,
+	}
+	for name, err := range o.errs {
+		r.Errors[name] = err
+	}
+	for name, d := range o.durations {
+		r.Durations[name] = d
+	}
+	if len(o.skipped) > 0 {
+		r.Skipped = append([]string(nil), o.skipped...)
+		sort.Strings(r.Skipped)
+	}
+	return r
+}
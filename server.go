@@ -0,0 +1,88 @@
+package exitplan
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// ManageServer will register the Shutdown of srv under name and track the requests
+//  in-flight on it, see InFlight. Keep-alives are disabled once the plan is terminating
+//  so clients reconnect elsewhere while draining.
+// The handler of srv is wrapped, it must be set before calling ManageServer.
+func (p *ExecutionPlan) ManageServer(name string, srv *http.Server) error {
+	if err := p.Add(name, srv.Shutdown); err != nil {
+		return err
+	}
+
+	handler := srv.Handler
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	srv.Handler = p.TrackInFlight(handler)
+
+	exit := p.NewExitChan()
+	go func() {
+		<-exit
+		srv.SetKeepAlivesEnabled(false)
+	}()
+
+	return nil
+}
+
+// ProbeMux is a router ManageServerWithProbes can mount the probes on,
+//  like *http.ServeMux. See ProbeMuxFunc for the routers returning a route.
+type ProbeMux interface {
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
+// ProbeMuxFunc adapts a func mounting a handler to a ProbeMux, for the routers whose
+//  HandleFunc returns a value, e.g. the *mux.Route of gorilla/mux:
+//
+//	exitplan.ProbeMuxFunc(func(pattern string, h func(http.ResponseWriter, *http.Request)) {
+//		m.HandleFunc(pattern, h).Methods(http.MethodGet, http.MethodHead)
+//	})
+type ProbeMuxFunc func(pattern string, handler func(http.ResponseWriter, *http.Request))
+
+// HandleFunc calls f(pattern, handler).
+func (f ProbeMuxFunc) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	f(pattern, handler)
+}
+
+// ManageServerWithProbes is ManageServer, also mounting HandlerFunc on "/readyz"
+//  and LivenessHandler on "/healthz" of mux, the router served by srv.
+func (p *ExecutionPlan) ManageServerWithProbes(name string, srv *http.Server, mux ProbeMux) error {
+	if err := p.ManageServer(name, srv); err != nil {
+		return err
+	}
+
+	mux.HandleFunc("/readyz", p.HandlerFunc)
+	mux.HandleFunc("/healthz", p.LivenessHandler)
+	return nil
+}
+
+// ManageServers will call ManageServer for each of the servers, keyed by name.
+// They all drain during the same GradePeriod and shut down within the same Timeout,
+//  HandlerFunc can be mounted on any of them. It stops at the first error.
+func (p *ExecutionPlan) ManageServers(servers map[string]*http.Server) error {
+	for name, srv := range servers {
+		if err := p.ManageServer(name, srv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TrackInFlight wraps h to count its requests in-flight, see InFlight.
+func (p *ExecutionPlan) TrackInFlight(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&p.inFlight, 1)
+		defer atomic.AddInt64(&p.inFlight, -1)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// InFlight returns the number of requests in-flight on the servers
+//  and handlers tracked by the plan.
+func (p *ExecutionPlan) InFlight() int64 {
+	return atomic.LoadInt64(&p.inFlight)
+}
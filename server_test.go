@@ -0,0 +1,41 @@
+package exitplan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestManageServerWithProbes(t *testing.T) {
+	serveMux, funcMux := http.NewServeMux(), http.NewServeMux()
+	tests := []struct {
+		name   string
+		router http.Handler
+		mux    ProbeMux
+	}{
+		{"ServeMux", serveMux, serveMux},
+		{"ProbeMuxFunc", funcMux, ProbeMuxFunc(func(pattern string, h func(http.ResponseWriter, *http.Request)) {
+			funcMux.HandleFunc(pattern, h)
+		})},
+	}
+
+	for _, tt := range tests {
+		p, _ := newTestPlan(0, time.Second)
+		srv := &http.Server{Handler: tt.router}
+		if err := p.ManageServerWithProbes("http", srv, tt.mux); err != nil {
+			t.Fatalf("%s: ManageServerWithProbes() = %v", tt.name, err)
+		}
+
+		for _, path := range []string{"/readyz", "/healthz"} {
+			rec := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+				t.Errorf("%s: GET %s = %d %q, want 200 \"ok\"", tt.name, path, rec.Code, rec.Body.String())
+			}
+		}
+		if names := p.Names(); len(names) != 1 || names[0] != "http" {
+			t.Errorf("%s: Names() = %v, want [http]", tt.name, names)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+// +build !windows
+
+package exitplan
+
+import (
+	"errors"
+)
+
+// RunService is only supported on Windows.
+func (p *ExecutionPlan) RunService(name string) error {
+	return errors.New("exitplan: windows services are only supported on windows")
+}
@@ -0,0 +1,70 @@
+//go:build windows
+// +build windows
+
+package exitplan
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+// servicePendingInterval is the delay between the StopPending updates of RunService.
+const servicePendingInterval = time.Second
+
+// RunService will run the plan as the Windows service name, blocking until it's stopped.
+// A Stop or Shutdown control from the service control manager runs the shutdown sequence
+//  like a signal does, the service reporting StopPending until it has completed.
+// The controls are still answered while stopping, the CheckPoint of the StopPending
+//  status increasing every servicePendingInterval for the manager to see the progress.
+func (p *ExecutionPlan) RunService(name string) error {
+	if err := svc.Run(name, &serviceHandler{plan: p}); err != nil {
+		return err
+	}
+	return p.Err()
+}
+
+// serviceHandler maps the controls of the service control manager to the plan.
+type serviceHandler struct {
+	plan *ExecutionPlan
+}
+
+func (h *serviceHandler) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	const accepts = svc.AcceptStop | svc.AcceptShutdown
+
+	changes <- svc.Status{State: svc.StartPending}
+	status := svc.Status{State: svc.Running, Accepts: accepts}
+	changes <- status
+
+	// pending ticks once stopping, nil until then.
+	var pending <-chan time.Time
+	for {
+		select {
+		case c := <-r:
+			switch c.Cmd {
+			case svc.Interrogate:
+				changes <- status
+			case svc.Stop, svc.Shutdown:
+				if pending != nil {
+					// Already stopping.
+					continue
+				}
+				gradePeriod, timeout := h.plan.timeouts()
+				wait := (gradePeriod + timeout) / time.Millisecond
+				status = svc.Status{State: svc.StopPending, WaitHint: uint32(wait)}
+				changes <- status
+
+				ticker := time.NewTicker(servicePendingInterval)
+				defer ticker.Stop()
+				pending = ticker.C
+				go h.plan.fire(context.Background(), nil)
+			}
+		case <-pending:
+			status.CheckPoint++
+			changes <- status
+		case <-h.plan.Done():
+			return false, 0
+		}
+	}
+}
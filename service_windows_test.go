@@ -0,0 +1,56 @@
+//go:build windows
+// +build windows
+
+package exitplan
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+func TestServiceAnswersControlsWhileStopping(t *testing.T) {
+	p, _ := newTestPlan(0, time.Second)
+	release := make(chan struct{})
+	_ = p.Add("slow", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	r := make(chan svc.ChangeRequest)
+	changes := make(chan svc.Status, 16)
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		(&serviceHandler{plan: p}).Execute(nil, r, changes)
+	}()
+
+	send := func(cmd svc.Cmd) {
+		t.Helper()
+		select {
+		case r <- svc.ChangeRequest{Cmd: cmd}:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("the %v control was not received", cmd)
+		}
+	}
+	send(svc.Stop)
+	send(svc.Interrogate)
+
+	// StartPending, Running, StopPending and the answer to Interrogate.
+	var last svc.Status
+	for i := 0; i < 4; i++ {
+		last = <-changes
+	}
+	if last.State != svc.StopPending {
+		t.Errorf("Interrogate answered %v, want %v", last.State, svc.StopPending)
+	}
+
+	close(release)
+	select {
+	case <-returned:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Execute has not returned once the plan was done")
+	}
+}
@@ -0,0 +1,53 @@
+package exitplan
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrShutdownStarted is returned when changing a setting once the shutdown has begun.
+var ErrShutdownStarted = errors.New("exitplan: shutdown has already started")
+
+// SetTimeout will set the Timeout while the plan is running, safe to call concurrently.
+// It returns ErrShutdownStarted once a signal or Shutdown has been received, changes
+//  after that point are ignored and the shutdown keeps the timeout it started with.
+func (p *ExecutionPlan) SetTimeout(d time.Duration) error {
+	p.settingsMutex.Lock()
+	defer p.settingsMutex.Unlock()
+
+	if p.shutdownStarted() {
+		return ErrShutdownStarted
+	}
+	p.Timeout = d
+	return nil
+}
+
+// SetGradePeriod will set the GradePeriod while the plan is running, see SetTimeout.
+func (p *ExecutionPlan) SetGradePeriod(d time.Duration) error {
+	p.settingsMutex.Lock()
+	defer p.settingsMutex.Unlock()
+
+	if p.shutdownStarted() {
+		return ErrShutdownStarted
+	}
+	p.GradePeriod = d
+	return nil
+}
+
+// timeouts returns the GradePeriod and Timeout as set by SetGradePeriod and SetTimeout.
+func (p *ExecutionPlan) timeouts() (gradePeriod, timeout time.Duration) {
+	p.settingsMutex.RLock()
+	defer p.settingsMutex.RUnlock()
+
+	return p.GradePeriod, p.Timeout
+}
+
+// shutdownStarted returns whether the shutdown has been requested.
+func (p *ExecutionPlan) shutdownStarted() bool {
+	select {
+	case <-p.shutdownRequested:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,47 @@
+package exitplan
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetGradePeriodRacingReaders(t *testing.T) {
+	p := NewPlanWithSignals(time.Second, 2*time.Second)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_ = p.SetGradePeriod(time.Duration(i) * time.Millisecond)
+			_ = p.SetTimeout(time.Duration(i) * time.Second)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_ = p.Snapshot()
+			_ = p.Describe()
+			_ = p.Validate()
+		}
+	}()
+	wg.Wait()
+}
+
+func TestApplyConfigOnceShutdownStarted(t *testing.T) {
+	p, _ := newTestPlan(0, time.Second)
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+
+	timeout := 5 * time.Second
+	if err := p.ApplyConfig(&PlanConfig{Timeout: &timeout}, nil); !errors.Is(err, ErrShutdownStarted) {
+		t.Errorf("ApplyConfig() = %v, want %v", err, ErrShutdownStarted)
+	}
+	if _, got := p.timeouts(); got != time.Second {
+		t.Errorf("Timeout = %v, want it unchanged at %v", got, time.Second)
+	}
+}
@@ -0,0 +1,328 @@
+package exitplan
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotReady can be returned by an ExitOperation that can't complete yet,
+//  it will be invoked again after RetryNotReadyDelay until the timeout has elapsed.
+var ErrNotReady = errors.New("exitplan: not ready")
+
+// ErrAbandoned is recorded for a callback that has not returned before its deadline.
+// The callback is left running while the shutdown proceeds.
+var ErrAbandoned = errors.New("exitplan: callback abandoned after its deadline")
+
+// ErrCanceled is recorded for a callback that was running, or yet to run, when the
+//  context of the shutdown was canceled, see Shutdown.
+var ErrCanceled = errors.New("exitplan: callback canceled with the shutdown")
+
+// stuckGrace is how long a callback is given to return past its deadline before it's
+//  abandoned, runConcurrent waits twice that for them to be abandoned by themselves.
+const stuckGrace = 100 * time.Millisecond
+
+// shutdown is the state of a single execution of the exit operations.
+type shutdown struct {
+	ctx        context.Context
+	deadline   time.Time
+	retryDelay time.Duration
+	results    *outcome
+
+	// maxConcurrency limits the callbacks running at once in runConcurrent, zero is unlimited.
+	maxConcurrency int
+	// callbackTimeout bounds each callback like WithTimeout, zero is unbounded.
+	callbackTimeout time.Duration
+
+	logger    Logger
+	level     LogLevel
+	groupLogs bool
+	logMutex  sync.Mutex
+	clock     Clock
+	executor  Executor
+	ran       *ranGuard
+}
+
+// callbackLogger returns the Logger for a callback and the func to call once it has
+//  completed, buffering its lines when groupLogs is set.
+func (s *shutdown) callbackLogger() (Logger, func()) {
+	if !s.groupLogs {
+		return s.logger, func() {}
+	}
+	b := &bufferedLogger{out: s.logger, mu: &s.logMutex}
+	return b, b.flush
+}
+
+// dispose will execute the callback and log the outcome.
+func (s *shutdown) dispose(ctx context.Context, c *callback, log Logger) error {
+	if !c.quiet {
+		s.logAt(log, LevelDebug, "disposing: %s", c.label())
+	}
+	if err := s.invoke(ctx, c, log); err != nil {
+		s.logAt(log, LevelError, "%s: dispose failed: %s", c.label(), err.Error())
+		return err
+	}
+	if !c.quiet {
+		s.logAt(log, LevelDebug, "%s was disposed gracefully", c.name)
+	}
+	return nil
+}
+
+// invoke will call the ExitOperation of c, calling it again for as long as it
+//  returns ErrNotReady and the deadline allows for it. Other errors are retried
+//  with a backoff as set by WithRetry, giving up once the deadline is hit.
+func (s *shutdown) invoke(ctx context.Context, c *callback, log Logger) error {
+	backoff := c.backoff
+	for attempt := 0; ; {
+		err := c.op(ctx)
+		if err == nil {
+			return nil
+		}
+
+		notReady := errors.Is(err, ErrNotReady)
+		delay := s.retryDelay
+		if !notReady {
+			if attempt >= c.retries {
+				return err
+			}
+			attempt++
+			delay = backoff
+			backoff *= 2
+		}
+
+		if s.clock.Now().Add(delay).After(s.deadline) {
+			if !notReady {
+				s.logAt(log, LevelWarn, "gave up retrying %s: deadline exceeded", c.name)
+			}
+			return err
+		}
+
+		if !notReady {
+			s.logAt(log, LevelWarn, "%s: failed, retrying in %d ms (%d/%d): %s", c.name, delay.Milliseconds(), attempt, c.retries, err.Error())
+		} else if !c.quiet {
+			s.logAt(log, LevelDebug, "%s: not ready, retrying in %d ms", c.name, delay.Milliseconds())
+		}
+		select {
+		case <-s.clock.After(delay):
+		case <-ctx.Done():
+			if !notReady {
+				s.logAt(log, LevelWarn, "gave up retrying %s: deadline exceeded", c.name)
+			}
+			return err
+		}
+	}
+}
+
+// runConcurrent will execute the callbacks async to allow for a faster shutdown process.
+// It returns once all of them have completed, or shortly after the deadline when some
+//  are still stuck (e.g. waiting on a blocked Executor), recording them as abandoned.
+// With maxConcurrency the callbacks still waiting for a slot at the deadline are
+//  not started, they're recorded as abandoned.
+func (s *shutdown) runConcurrent(callbacks []*callback) {
+	var slots chan struct{}
+	if s.maxConcurrency > 0 {
+		slots = make(chan struct{}, s.maxConcurrency)
+	}
+
+	var pendingMutex sync.Mutex
+	pending := make(map[*callback]struct{}, len(callbacks))
+	for _, c := range callbacks {
+		pending[c] = struct{}{}
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		defer close(finished)
+		defer wg.Wait()
+
+		expired := s.clock.After(s.deadline.Sub(s.clock.Now()))
+		for i, c := range callbacks {
+			if slots != nil {
+				select {
+				case slots <- struct{}{}:
+				case <-expired:
+				case <-s.ctx.Done():
+				}
+			}
+			if s.pastDeadline() {
+				// Don't start the callbacks still waiting for a slot.
+				s.notStarted(callbacks[i:], pending, &pendingMutex)
+				return
+			}
+
+			wg.Add(1)
+			c := c
+			s.executor.Submit(func() {
+				defer wg.Done()
+				s.record(c)
+				pendingMutex.Lock()
+				delete(pending, c)
+				pendingMutex.Unlock()
+				if slots != nil {
+					<-slots
+				}
+			})
+		}
+	}()
+
+	select {
+	case <-finished:
+		return
+	case <-s.clock.After(s.deadline.Sub(s.clock.Now()) + 2*stuckGrace):
+	case <-s.ctx.Done():
+		// Give the callbacks the chance to return, or be abandoned, by themselves.
+		select {
+		case <-finished:
+			return
+		case <-s.clock.After(2 * stuckGrace):
+		}
+	}
+
+	pendingMutex.Lock()
+	defer pendingMutex.Unlock()
+
+	stuck := make([]*callback, 0, len(pending))
+	for c := range pending {
+		stuck = append(stuck, c)
+	}
+	sort.Sort(byIndex(stuck))
+
+	for _, c := range stuck {
+		if s.canceled() {
+			s.logAt(s.logger, LevelWarn, "%s: still running after the cancellation, proceeding", c.name)
+		} else {
+			s.logAt(s.logger, LevelWarn, "%s: still running after the deadline, proceeding", c.name)
+		}
+		s.results.record(c.name, s.abandoned(), 0)
+		delete(pending, c)
+	}
+}
+
+// pastDeadline reports if the deadline has passed or the context of the shutdown
+//  is done, the callbacks not started by then are not to be started.
+func (s *shutdown) pastDeadline() bool {
+	return !s.clock.Now().Before(s.deadline) || s.ctx.Err() != nil
+}
+
+// notStarted will record the callbacks that were not started before the deadline
+//  as abandoned, unless they have been recorded already.
+func (s *shutdown) notStarted(callbacks []*callback, pending map[*callback]struct{}, mu *sync.Mutex) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, c := range callbacks {
+		if _, ok := pending[c]; !ok {
+			continue
+		}
+		delete(pending, c)
+		s.logAt(s.logger, LevelWarn, "%s: not started before the deadline, skipping", c.name)
+		s.results.record(c.name, s.abandoned(), 0)
+	}
+}
+
+// canceled reports if the context of the shutdown was canceled, rather than done
+//  because of its deadline.
+func (s *shutdown) canceled() bool {
+	return errors.Is(s.ctx.Err(), context.Canceled)
+}
+
+// abandoned returns the error recorded for a callback that has not returned in time.
+func (s *shutdown) abandoned() error {
+	if s.canceled() {
+		return ErrCanceled
+	}
+	return ErrAbandoned
+}
+
+// runSequential will execute the callbacks one-by-one in the given order.
+func (s *shutdown) runSequential(callbacks []*callback) {
+	for _, c := range callbacks {
+		s.record(c)
+	}
+}
+
+// record will dispose the callback and record its outcome.
+// Each callback is given its own context derived from the one of the shutdown,
+//  canceled once it returns, so a callback can't cancel the context of another.
+// Its deadline is the one of the callback when it's bound by WithTimeout.
+// A callback still running at its deadline is abandoned, so a hung callback
+//  can't block the rest of the shutdown. One reached past the deadline is not
+//  started. Once the context of the shutdown is canceled the running callbacks
+//  are abandoned and the others are not started. It's claimed only once it's about
+//  to start, so a callback skipped here can still be run by the forced exit.
+func (s *shutdown) record(c *callback) {
+	if s.ran.started(c) {
+		s.logAt(s.logger, LevelDebug, "%s has already been started, skipping", c.name)
+		return
+	}
+	if s.canceled() {
+		s.logAt(s.logger, LevelWarn, "%s: skipped, the shutdown was canceled", c.name)
+		s.results.record(c.name, ErrCanceled, 0)
+		return
+	}
+	if s.pastDeadline() {
+		s.logAt(s.logger, LevelWarn, "%s: not started before the deadline, skipping", c.name)
+		s.results.record(c.name, ErrAbandoned, 0)
+		return
+	}
+	if c.cond != nil && !c.cond() {
+		s.logAt(s.logger, LevelInfo, "skipped: %s", c.name)
+		s.results.skip(c.name)
+		return
+	}
+	if !s.ran.claim(c) {
+		s.logAt(s.logger, LevelDebug, "%s has already been started, skipping", c.name)
+		return
+	}
+
+	start := s.clock.Now()
+	log, flush := s.callbackLogger()
+	defer flush()
+
+	deadline := s.deadline
+	for _, t := range []time.Duration{c.timeout, s.callbackTimeout} {
+		if t > 0 && start.Add(t).Before(deadline) {
+			deadline = start.Add(t)
+		}
+	}
+	timeout := s.clock.After(deadline.Sub(start))
+
+	// Carry the timeout of the callback in its context, for it to budget its work.
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if deadline.Before(s.deadline) {
+		ctx, cancel = withClockDeadline(s.ctx, s.clock, deadline)
+	} else {
+		ctx, cancel = context.WithCancel(s.ctx)
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer cancel()
+		done <- s.dispose(ctx, c, log)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-timeout:
+		// Prefer the outcome of a callback that has returned on its deadline.
+		select {
+		case err = <-done:
+		case <-s.clock.After(stuckGrace):
+			s.logAt(log, LevelWarn, "%s: abandoned after %d ms", c.name, s.clock.Now().Sub(start).Milliseconds())
+			err = ErrAbandoned
+		}
+	case <-s.ctx.Done():
+		// Prefer the outcome of a callback that has returned on the cancellation.
+		select {
+		case err = <-done:
+		case <-s.clock.After(stuckGrace):
+			s.logAt(log, LevelWarn, "%s: abandoned after %d ms", c.name, s.clock.Now().Sub(start).Milliseconds())
+			err = s.abandoned()
+		}
+	}
+	s.results.record(c.name, err, s.clock.Now().Sub(start))
+}
@@ -0,0 +1,146 @@
+package exitplan
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+)
+
+// newTestPlan returns a plan on a FakeClock that doesn't log nor exit the process.
+func newTestPlan(gradePeriod, timeout time.Duration) (*ExecutionPlan, *FakeClock) {
+	clock := NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	p := NewPlanWithSignals(gradePeriod, timeout)
+	p.Clock = clock
+	p.DisableForceExit = true
+	p.Logger = log.New(ioutil.Discard, "", 0)
+	return p, clock
+}
+
+// awaitTimers will wait for n timers to be pending on clock.
+func awaitTimers(t *testing.T, clock *FakeClock, n int) {
+	t.Helper()
+	for start := time.Now(); clock.Pending() < n; {
+		if time.Since(start) > 2*time.Second {
+			t.Fatalf("%d timers pending, want %d", clock.Pending(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCallbackContextDeadline(t *testing.T) {
+	p, clock := newTestPlan(0, 5*time.Second)
+	start := clock.Now()
+
+	var deadline time.Time
+	var ok bool
+	_ = p.AddWithOptions("bounded", func(ctx context.Context) error {
+		deadline, ok = ctx.Deadline()
+		return nil
+	}, WithTimeout(500*time.Millisecond))
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+	if !ok || !deadline.Equal(start.Add(500*time.Millisecond)) {
+		t.Errorf("ctx.Deadline() = %v, %v, want %v", deadline, ok, start.Add(500*time.Millisecond))
+	}
+}
+
+func TestCallbackRespectingDeadlineIsNotAbandoned(t *testing.T) {
+	p, clock := newTestPlan(0, 5*time.Second)
+	_ = p.AddWithOptions("bounded", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(500*time.Millisecond))
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p.Shutdown(context.Background())
+	}()
+
+	// The ctx deadline, the abandon timer and the stuck timer of runConcurrent.
+	awaitTimers(t, clock, 3)
+	clock.Advance(500 * time.Millisecond)
+
+	<-errc
+	if err := p.Result().Errors["bounded"]; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error of bounded = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// shutdownAndAdvance will run the shutdown of p, advancing clock by step until it's done.
+func shutdownAndAdvance(t *testing.T, p *ExecutionPlan, clock *FakeClock, step time.Duration) error {
+	t.Helper()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p.Shutdown(context.Background())
+	}()
+
+	for start := time.Now(); ; {
+		select {
+		case err := <-errc:
+			return err
+		case <-time.After(time.Millisecond):
+		}
+		if time.Since(start) > 2*time.Second {
+			t.Fatal("shutdown has not completed")
+		}
+		if clock.Pending() > 0 {
+			clock.Advance(step)
+		}
+	}
+}
+
+func TestWaitingForSlotPastDeadlineIsNotStarted(t *testing.T) {
+	p, clock := newTestPlan(0, time.Second)
+	p.MaxConcurrency = 1
+
+	hung := make(chan struct{})
+	defer close(hung)
+	_ = p.Add("hung", func(ctx context.Context) error {
+		<-hung
+		return nil
+	})
+	started := make(chan struct{}, 1)
+	_ = p.Add("late", func(ctx context.Context) error {
+		started <- struct{}{}
+		return nil
+	})
+
+	_ = shutdownAndAdvance(t, p, clock, 50*time.Millisecond)
+
+	select {
+	case <-started:
+		t.Error("late was started past the deadline")
+	default:
+	}
+	for _, name := range []string{"hung", "late"} {
+		if err := p.Result().Errors[name]; !errors.Is(err, ErrAbandoned) {
+			t.Errorf("error of %s = %v, want %v", name, err, ErrAbandoned)
+		}
+	}
+}
+
+func TestDeadlineCutsRetriesShort(t *testing.T) {
+	p, clock := newTestPlan(0, time.Second)
+
+	failure := errors.New("unavailable")
+	attempts := 0
+	_ = p.AddWithOptions("flaky", func(ctx context.Context) error {
+		attempts++
+		return failure
+	}, WithRetry(10, 400*time.Millisecond))
+
+	_ = shutdownAndAdvance(t, p, clock, 100*time.Millisecond)
+
+	// Retried after 400ms, the next retry 800ms later would be past the deadline.
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+	if err := p.Result().Errors["flaky"]; err != failure {
+		t.Errorf("error of flaky = %v, want %v", err, failure)
+	}
+}
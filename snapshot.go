@@ -0,0 +1,83 @@
+package exitplan
+
+import (
+	"sort"
+)
+
+// PlanSnapshot is the configuration and runtime state of a plan at a point in time,
+//  see Snapshot.
+type PlanSnapshot struct {
+	Signals     []string           `json:"signals"`
+	GradePeriod string             `json:"grade_period"`
+	Timeout     string             `json:"timeout"`
+	State       string             `json:"state"`
+	Terminating bool               `json:"terminating"`
+	InFlight    int64              `json:"in_flight"`
+	Signal      string             `json:"signal,omitempty"`
+	Callbacks   []CallbackSnapshot `json:"callbacks"`
+	Final       bool               `json:"final"`
+}
+
+// CallbackSnapshot is a registered callback along with its options.
+type CallbackSnapshot struct {
+	Name       string            `json:"name"`
+	Sequential bool              `json:"sequential,omitempty"`
+	Quiet      bool              `json:"quiet,omitempty"`
+	PreStop    bool              `json:"pre_stop,omitempty"`
+	Lease      bool              `json:"lease,omitempty"`
+	MustRun    bool              `json:"must_run,omitempty"`
+	Timeout    string            `json:"timeout,omitempty"`
+	Meta       map[string]string `json:"meta,omitempty"`
+}
+
+// Snapshot returns the configuration and runtime state of the plan, to be encoded
+//  as JSON for a debug endpoint. It's safe to call concurrently with the shutdown.
+func (p *ExecutionPlan) Snapshot() PlanSnapshot {
+	gradePeriod, timeout := p.timeouts()
+	s := PlanSnapshot{
+		GradePeriod: gradePeriod.String(),
+		Timeout:     timeout.String(),
+		State:       p.State().String(),
+		Terminating: p.IsTerminating(),
+		InFlight:    p.InFlight(),
+		Final:       p.finalCallback != nil,
+	}
+	for _, sig := range p.WatchedSignals() {
+		s.Signals = append(s.Signals, sig.String())
+	}
+	if sig := p.TriggeringSignal(); sig != nil {
+		s.Signal = sig.String()
+	}
+
+	p.callbacksMutex.RLock()
+	callbacks := make([]*callback, 0, len(p.callbacks))
+	for _, c := range p.callbacks {
+		callbacks = append(callbacks, c)
+	}
+	p.callbacksMutex.RUnlock()
+	sort.Sort(byIndex(callbacks))
+
+	s.Callbacks = make([]CallbackSnapshot, len(callbacks))
+	for i, c := range callbacks {
+		s.Callbacks[i] = CallbackSnapshot{
+			Name:       c.name,
+			Sequential: c.sequential,
+			Quiet:      c.quiet,
+			PreStop:    c.preStop,
+			Lease:      c.lease,
+			MustRun:    c.mustRun,
+		}
+		if len(c.meta) > 0 {
+			meta := make(map[string]string, len(c.meta))
+			for k, v := range c.meta {
+				meta[k] = v
+			}
+			s.Callbacks[i].Meta = meta
+		}
+		if c.timeout > 0 {
+			s.Callbacks[i].Timeout = c.timeout.String()
+		}
+	}
+
+	return s
+}
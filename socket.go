@@ -0,0 +1,131 @@
+package exitplan
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+)
+
+// statusMessage is a line written by the status socket.
+type statusMessage struct {
+	State       string `json:"state"`
+	Terminating bool   `json:"terminating"`
+}
+
+// statusSocket serves the State of a plan over a Unix domain socket.
+type statusSocket struct {
+	plan     *ExecutionPlan
+	path     string
+	listener net.Listener
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+}
+
+// ListenStatusSocket will serve the State of the plan on the Unix domain socket at path,
+//  e.g. for a sidecar. Each client is sent the current State as a line of JSON,
+//  then another line every time it changes until the plan is Done:
+//
+//   {"state":"draining","terminating":true}
+//
+// Closing the returned io.Closer stops serving and removes the socket file.
+func (p *ExecutionPlan) ListenStatusSocket(path string) (io.Closer, error) {
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &statusSocket{
+		plan:     p,
+		path:     path,
+		listener: l,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	go s.serve()
+
+	return s, nil
+}
+
+func (s *statusSocket) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+func (s *statusSocket) handle(conn net.Conn) {
+	changes := s.plan.StateChanges()
+	defer func() {
+		s.plan.removeStateListener(changes)
+
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	enc := json.NewEncoder(conn)
+	write := func(state State) error {
+		return enc.Encode(statusMessage{
+			State:       state.String(),
+			Terminating: s.plan.IsTerminating(),
+		})
+	}
+
+	// Detect the client going away, nothing is expected to be read.
+	gone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(ioutil.Discard, conn)
+		close(gone)
+	}()
+
+	if err := write(s.plan.State()); err != nil {
+		return
+	}
+	for {
+		select {
+		case state, ok := <-changes:
+			if !ok {
+				return
+			}
+			if err := write(state); err != nil {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
+
+// Close will stop accepting clients, disconnect the connected ones and remove the socket file.
+func (s *statusSocket) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	if rmErr := os.Remove(s.path); rmErr != nil && !os.IsNotExist(rmErr) && err == nil {
+		err = rmErr
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+package exitplan
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// State is the stage of the shutdown an ExecutionPlan is in.
+type State int32
+
+const (
+	// Running is the state before an exit signal has been received.
+	Running State = iota
+	// Draining is the state during the GradePeriod, allowing connections to drain.
+	Draining
+	// Disposing is the state while the exit operations are running.
+	Disposing
+	// Finalizing is the state while the final callback is running.
+	Finalizing
+	// Done is the state once the shutdown has completed.
+	Done
+	// ForcedExit is the state when the Timeout has elapsed and the process is exiting.
+	ForcedExit
+)
+
+var stateNames = map[State]string{
+	Running:    "running",
+	Draining:   "draining",
+	Disposing:  "disposing",
+	Finalizing: "finalizing",
+	Done:       "done",
+	ForcedExit: "forced_exit",
+}
+
+func (s State) String() string {
+	if name, ok := stateNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// State returns the current State of the plan.
+func (p *ExecutionPlan) State() State {
+	return State(atomic.LoadInt32(&p.state))
+}
+
+// StateChanges will return a new chan receiving every State the plan moves to.
+// The chan is buffered and a slow reader may miss states, the State method will
+//  always report the current one. It's closed once the plan is Done.
+func (p *ExecutionPlan) StateChanges() <-chan State {
+	c := make(chan State, 8)
+
+	p.stateLock.Lock()
+	if p.State() == Done {
+		close(c)
+	} else {
+		p.stateListeners = append(p.stateListeners, c)
+	}
+	p.stateLock.Unlock()
+
+	return c
+}
+
+// removeStateListener will stop c from receiving the State changes.
+func (p *ExecutionPlan) removeStateListener(c <-chan State) {
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	for i, l := range p.stateListeners {
+		if l == c {
+			p.stateListeners = append(p.stateListeners[:i], p.stateListeners[i+1:]...)
+			return
+		}
+	}
+}
+
+// setState will move the plan to s and notify the StateChanges listeners.
+func (p *ExecutionPlan) setState(s State) {
+	atomic.StoreInt32(&p.state, int32(s))
+	p.tracePhase(s)
+
+	p.stateLock.Lock()
+	defer p.stateLock.Unlock()
+
+	for _, c := range p.stateListeners {
+		select {
+		case c <- s:
+		default:
+		}
+	}
+
+	if s == Done {
+		for _, c := range p.stateListeners {
+			close(c)
+		}
+		p.stateListeners = nil
+	}
+}
+
+// ShutdownMode overrides the timers of the plan for a shutdown triggered by a signal,
+//  see ExecutionPlan.SignalMode. The nil fields keep the ones of the plan.
+type ShutdownMode struct {
+	GradePeriod *time.Duration
+	Timeout     *time.Duration
+	// CallbackTimeout is the longest each callback may run for, like WithTimeout.
+	// Zero is only bound by the Timeout.
+	CallbackTimeout time.Duration
+}
+
+// SignalAction is what the plan does when it receives a signal.
+type SignalAction int
+
+const (
+	// Drain will begin the shutdown, it has no effect once it has begun.
+	Drain SignalAction = iota
+	// ForceExit will exit the process immediately.
+	ForceExit
+	// Ignore will ignore the signal, it's still counted.
+	Ignore
+)
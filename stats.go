@@ -0,0 +1,82 @@
+package exitplan
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the counters of a plan, to tune its GradePeriod from production data.
+type Stats struct {
+	// InFlight is the number of requests tracked by TrackInFlight being served.
+	InFlight int64
+	// InFlightAfterDrain is the InFlight count when the GradePeriod has ended,
+	//  -1 until it has. Above zero the GradePeriod was too short to drain them.
+	InFlightAfterDrain int64
+	// PeakGoroutines is the highest number of goroutines sampled during the shutdown,
+	//  zero without a GoroutineThreshold.
+	PeakGoroutines int64
+}
+
+// Stats returns the current Stats of the plan.
+func (p *ExecutionPlan) Stats() Stats {
+	return Stats{
+		InFlight:           p.InFlight(),
+		InFlightAfterDrain: atomic.LoadInt64(&p.inFlightAfterDrain),
+		PeakGoroutines:     atomic.LoadInt64(&p.peakGoroutines),
+	}
+}
+
+// recordOutcome will call RecordOutcome once, with the time since the plan is terminating.
+func (p *ExecutionPlan) recordOutcome(clean bool) {
+	if p.RecordOutcome == nil {
+		return
+	}
+	p.outcomeOnce.Do(func() {
+		p.RecordOutcome(clean, p.terminatingFor())
+	})
+}
+
+// recordDrain will keep how long the drain took and the InFlight count at the end
+//  of the grade period, warning when requests are still being served.
+func (p *ExecutionPlan) recordDrain(gradePeriod, drained time.Duration) {
+	atomic.StoreInt64(&p.drained, int64(drained))
+
+	n := p.InFlight()
+	atomic.StoreInt64(&p.inFlightAfterDrain, n)
+	if n > 0 {
+		p.warnf("drain incomplete: %d requests still active after %s grace", n, gradePeriod)
+	}
+}
+
+// goroutineSampleInterval is the delay between the samples of watchGoroutines.
+const goroutineSampleInterval = 100 * time.Millisecond
+
+// watchGoroutines will sample the number of goroutines until the plan is done,
+//  keeping the peak and warning once when it's above the GoroutineThreshold.
+func (p *ExecutionPlan) watchGoroutines() {
+	if p.GoroutineThreshold <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(goroutineSampleInterval)
+	defer ticker.Stop()
+
+	warned := false
+	for {
+		n := int64(runtime.NumGoroutine())
+		if n > atomic.LoadInt64(&p.peakGoroutines) {
+			atomic.StoreInt64(&p.peakGoroutines, n)
+		}
+		if n > int64(p.GoroutineThreshold) && !warned {
+			p.warnf("%d goroutines are running during the shutdown, above %d", n, p.GoroutineThreshold)
+			warned = true
+		}
+
+		select {
+		case <-ticker.C:
+		case <-p.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package exitplan
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// signalName returns the name of sig like "SIGTERM", "shutdown" when it's nil.
+func signalName(sig os.Signal) string {
+	if sig == nil {
+		return "shutdown"
+	}
+	for name, s := range signalNames {
+		if s == sig {
+			return name
+		}
+	}
+	return sig.String()
+}
+
+// logSummary will log a single line summing up the completed shutdown r:
+//
+//   exitplan: shutdown complete in 4.2s (drained 3.1s, 5/5 callbacks ok, final ok, trigger=SIGTERM)
+func (p *ExecutionPlan) logSummary(r Result) {
+	parts := []string{
+		"drained " + time.Duration(atomic.LoadInt64(&p.drained)).Round(time.Millisecond).String(),
+		callbacksOK(r),
+	}
+	if len(r.Skipped) > 0 {
+		parts = append(parts, fmt.Sprintf("%d skipped", len(r.Skipped)))
+	}
+	if _, ok := r.Durations["final"]; ok {
+		if r.Errors["final"] != nil {
+			parts = append(parts, "final failed")
+		} else {
+			parts = append(parts, "final ok")
+		}
+	}
+	parts = append(parts, "trigger="+signalName(r.Signal))
+
+	p.logf("exitplan: shutdown complete in %s (%s)", p.terminatingFor().Round(time.Millisecond), strings.Join(parts, ", "))
+}
+
+// logPlanned will log a single line with the callbacks that will run for sig, in the
+//  order they're started:
+//
+//   exitplan: shutdown begun; will dispose: [http, db, cache]
+func (p *ExecutionPlan) logPlanned(sig os.Signal) {
+	concurrent, sequential := p.splitCallbacks()
+	concurrent, sequential = runningFor(concurrent, sig), runningFor(sequential, sig)
+
+	first, then := concurrent, sequential
+	if p.SequentialFirst {
+		first, then = sequential, concurrent
+	}
+	names := make([]string, 0, len(first)+len(then))
+	for _, c := range append(first, then...) {
+		names = append(names, c.name)
+	}
+
+	p.logf("exitplan: shutdown begun; will dispose: [%s]", strings.Join(names, ", "))
+}
+
+// logForcedSummary will log a single line summing up the shutdown forced after the
+//  timeout, with the callbacks still pending:
+//
+//   exitplan: shutdown forced after timeout, 3/5 callbacks ok, pending=[db,cache]
+func (p *ExecutionPlan) logForcedSummary(sig os.Signal) {
+	r := p.outcome.result(sig)
+
+	concurrent, sequential := p.splitCallbacks()
+	var pending []string
+	for _, c := range runningFor(append(concurrent, sequential...), sig) {
+		if _, ok := r.Durations[c.name]; !ok && !containsString(r.Skipped, c.name) {
+			pending = append(pending, c.name)
+		}
+	}
+
+	p.errorf("exitplan: shutdown forced after timeout, %s, pending=[%s]", callbacksOK(r), strings.Join(pending, ","))
+}
+
+func containsString(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// callbacksOK returns how many of the callbacks of r have succeeded, like "3/5 callbacks ok".
+func callbacksOK(r Result) string {
+	total, failed := 0, 0
+	for name := range r.Durations {
+		if name == "final" {
+			continue
+		}
+		total++
+		if r.Errors[name] != nil {
+			failed++
+		}
+	}
+	return fmt.Sprintf("%d/%d callbacks ok", total-failed, total)
+}
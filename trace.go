@@ -0,0 +1,121 @@
+package exitplan
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// ShutdownTrace is the timeline of a shutdown for postmortems, see EnableTrace.
+// The offsets are from Started and taken from the monotonic clock, so they're
+//  accurate even when the wall clock jumps during the shutdown.
+type ShutdownTrace struct {
+	Started   time.Time       `json:"started"`
+	Signal    string          `json:"signal"`
+	Phases    []TracePhase    `json:"phases"`
+	Callbacks []TraceCallback `json:"callbacks"`
+}
+
+// TracePhase is the State the plan has moved to, and when.
+type TracePhase struct {
+	State  string `json:"state"`
+	AtNano int64  `json:"at_ns"`
+}
+
+// TraceCallback is the run of a callback, and its error if it has failed.
+type TraceCallback struct {
+	Name      string `json:"name"`
+	StartNano int64  `json:"start_ns"`
+	EndNano   int64  `json:"end_ns"`
+	Error     string `json:"error,omitempty"`
+}
+
+// tracer records the ShutdownTrace of a plan.
+type tracer struct {
+	mu    sync.Mutex
+	start time.Time
+	trace ShutdownTrace
+}
+
+// EnableTrace will record the ShutdownTrace of the shutdown, to be retrieved with
+//  Trace and archived along with an incident. It must be called before Start, a
+//  plan without it records nothing.
+func (p *ExecutionPlan) EnableTrace() {
+	if p.tracer == nil {
+		p.tracer = &tracer{}
+	}
+}
+
+// Trace returns a copy of the ShutdownTrace recorded so far, the zero ShutdownTrace
+//  unless EnableTrace was called. It's safe to call concurrently with the shutdown.
+func (p *ExecutionPlan) Trace() ShutdownTrace {
+	if p.tracer == nil {
+		return ShutdownTrace{}
+	}
+
+	p.tracer.mu.Lock()
+	defer p.tracer.mu.Unlock()
+
+	t := p.tracer.trace
+	t.Phases = append([]TracePhase(nil), t.Phases...)
+	t.Callbacks = append([]TraceCallback(nil), t.Callbacks...)
+	return t
+}
+
+// traceBegin will start the trace of the shutdown triggered by sig.
+func (p *ExecutionPlan) traceBegin(sig os.Signal) {
+	if p.tracer == nil {
+		return
+	}
+
+	p.tracer.mu.Lock()
+	defer p.tracer.mu.Unlock()
+
+	p.tracer.start = p.clock().Now()
+	p.tracer.trace.Started = p.tracer.start
+	p.tracer.trace.Signal = signalName(sig)
+}
+
+// tracePhase will record the plan moving to s.
+func (p *ExecutionPlan) tracePhase(s State) {
+	if p.tracer == nil {
+		return
+	}
+
+	p.tracer.mu.Lock()
+	defer p.tracer.mu.Unlock()
+
+	p.tracer.trace.Phases = append(p.tracer.trace.Phases, TracePhase{
+		State:  s.String(),
+		AtNano: p.tracer.offset(p.clock().Now()),
+	})
+}
+
+// traceCallback will record the run of a callback as it completes.
+func (p *ExecutionPlan) traceCallback(r CallbackResult) {
+	if p.tracer == nil {
+		return
+	}
+
+	p.tracer.mu.Lock()
+	defer p.tracer.mu.Unlock()
+
+	end := p.clock().Now()
+	c := TraceCallback{
+		Name:      r.Name,
+		StartNano: p.tracer.offset(end.Add(-r.Duration)),
+		EndNano:   p.tracer.offset(end),
+	}
+	if r.Err != nil {
+		c.Error = r.Err.Error()
+	}
+	p.tracer.trace.Callbacks = append(p.tracer.trace.Callbacks, c)
+}
+
+// offset returns the nanoseconds from the start of the trace to at.
+func (t *tracer) offset(at time.Time) int64 {
+	if t.start.IsZero() {
+		return 0
+	}
+	return at.Sub(t.start).Nanoseconds()
+}
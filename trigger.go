@@ -0,0 +1,82 @@
+package exitplan
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// triggerEvent is a request to run the shutdown sequence, sig is nil when it
+//  wasn't requested by a signal.
+type triggerEvent struct {
+	ctx context.Context
+	sig os.Signal
+}
+
+// fire will request the shutdown from one of the trigger sources (the signals of
+//  Start, Shutdown, TriggerOnFile, ...), they all feed the triggers chan and the
+//  first one wins. It returns once the request was taken or the shutdown has begun.
+func (p *ExecutionPlan) fire(ctx context.Context, sig os.Signal) {
+	p.dispatchOnce.Do(func() {
+		go p.dispatch()
+	})
+
+	select {
+	case p.triggers <- triggerEvent{ctx: ctx, sig: sig}:
+	case <-p.shutdownRequested:
+	}
+}
+
+// Trigger will run the shutdown sequence as if sig was received, without a confirmation,
+//  returning the aggregated error once it has completed like Shutdown. It's for tests
+//  and trigger sources of your own, a nil sig is like calling Shutdown.
+func (p *ExecutionPlan) Trigger(ctx context.Context, sig os.Signal) error {
+	p.fire(ctx, sig)
+	<-p.done
+	return p.Err()
+}
+
+// dispatch will run the shutdown sequence for the first trigger received.
+func (p *ExecutionPlan) dispatch() {
+	t := <-p.triggers
+
+	p.settingsMutex.Lock()
+	close(p.shutdownRequested)
+	p.settingsMutex.Unlock()
+	defer p.recoverPanic(t.sig)
+	p.run(t.ctx, t.sig)
+}
+
+// FilePollInterval is the delay between the checks made by TriggerOnFile.
+var FilePollInterval = time.Second
+
+// TriggerOnFile will watch for path to be created, then run the shutdown sequence
+//  like Shutdown does. It's an alternative to signals for when a sidecar requests
+//  the drain by writing a sentinel file.
+// The watcher stops once the shutdown has been requested, or when stop is called.
+func (p *ExecutionPlan) TriggerOnFile(path string) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ticker := time.NewTicker(FilePollInterval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := os.Stat(path); err != nil {
+					continue
+				}
+				p.logf("%s has been created", path)
+				p.fire(context.Background(), nil)
+				return
+			case <-p.shutdownRequested:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return cancel
+}
@@ -0,0 +1,51 @@
+package exitplan
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Validate will check that the plan is consistent before it's started, returning an
+//  error listing every problem found, nil when there are none. It's to catch mistakes
+//  at startup rather than during the shutdown.
+func (p *ExecutionPlan) Validate() error {
+	var problems []string
+
+	gradePeriod, timeout := p.timeouts()
+	if timeout <= 0 {
+		problems = append(problems, fmt.Sprintf("timeout %s is not positive", timeout))
+	}
+	if gradePeriod < 0 {
+		problems = append(problems, fmt.Sprintf("grade period %s is negative", gradePeriod))
+	}
+	if timeout > 0 && timeout <= gradePeriod {
+		problems = append(problems, fmt.Sprintf("timeout %s is not longer than the grade period %s", timeout, gradePeriod))
+	}
+	if p.MaxConcurrency < 0 {
+		problems = append(problems, fmt.Sprintf("max concurrency %d is negative", p.MaxConcurrency))
+	}
+	if p.SuccessThreshold < 0 {
+		problems = append(problems, fmt.Sprintf("success threshold %g is negative", p.SuccessThreshold))
+	}
+
+	p.callbacksMutex.RLock()
+	var callbackProblems []string
+	for name, c := range p.callbacks {
+		if name == "" {
+			callbackProblems = append(callbackProblems, "a callback has an empty name")
+		}
+		if c.op == nil {
+			callbackProblems = append(callbackProblems, fmt.Sprintf("callback %q is nil", name))
+		}
+	}
+	p.callbacksMutex.RUnlock()
+	sort.Strings(callbackProblems)
+
+	problems = append(problems, callbackProblems...)
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New("exitplan: invalid plan: " + strings.Join(problems, "; "))
+}